@@ -0,0 +1,71 @@
+package rsql
+
+// Node is a node of a parsed rsql expression.
+type Node interface {
+	// Pos returns the position (byte offset) of the node within the parsed string.
+	Pos() int
+	// String returns the node as rsql string, see Format.
+	String() string
+}
+
+// AndNode represents nodes joined by the logical AND operator (;).
+type AndNode struct {
+	Position int
+	Children []Node
+}
+
+// Pos returns the position of the node.
+func (n *AndNode) Pos() int {
+	return n.Position
+}
+
+// String returns the node as rsql string, see Format.
+func (n *AndNode) String() string {
+	return Format(n)
+}
+
+// OrNode represents nodes joined by the logical OR operator (,).
+type OrNode struct {
+	Position int
+	Children []Node
+}
+
+// Pos returns the position of the node.
+func (n *OrNode) Pos() int {
+	return n.Position
+}
+
+// String returns the node as rsql string, see Format.
+func (n *OrNode) String() string {
+	return Format(n)
+}
+
+// ComparisonNode represents a single comparison like a==1.
+type ComparisonNode struct {
+	Position int
+	Key      string
+	Operator string
+	// Value is the value as given in the query,
+	// list values include their parentheses, e.g. (1,2,3).
+	Value string
+}
+
+// Pos returns the position of the node.
+func (n *ComparisonNode) Pos() int {
+	return n.Position
+}
+
+// String returns the node as rsql string, see Format.
+func (n *ComparisonNode) String() string {
+	return Format(n)
+}
+
+// operatorPos returns the position of the comparison's operator.
+func (n *ComparisonNode) operatorPos() int {
+	return n.Position + len(n.Key)
+}
+
+// valuePos returns the position of the comparison's value.
+func (n *ComparisonNode) valuePos() int {
+	return n.operatorPos() + len(n.Operator)
+}
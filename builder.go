@@ -0,0 +1,364 @@
+package rsql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Builder builds syntax trees of queries, e.g.
+//
+//	rsql.Eq("status", "A").And(rsql.Lt("qty", 30)).Or(rsql.IsNull("qty", true))
+//
+// results in status=="A";qty=lt=30,qty=null=true.
+// Errors, e.g. about values which cannot be written as rsql,
+// are returned when the query is built.
+type Builder struct {
+	node Node
+	err  error
+}
+
+// Raw is a value which is written as given, e.g. a function call like now().
+type Raw string
+
+// And returns a builder joining the given builders by the logical AND operator.
+func And(builders ...*Builder) *Builder {
+	return group(builders, true)
+}
+
+// Or returns a builder joining the given builders by the logical OR operator.
+func Or(builders ...*Builder) *Builder {
+	return group(builders, false)
+}
+
+// And returns a builder joining the builder and the given ones by the logical AND operator.
+func (b *Builder) And(others ...*Builder) *Builder {
+	return And(append([]*Builder{b}, others...)...)
+}
+
+// Or returns a builder joining the builder and the given ones by the logical OR operator.
+func (b *Builder) Or(others ...*Builder) *Builder {
+	return Or(append([]*Builder{b}, others...)...)
+}
+
+// group returns a builder joining the given builders by the logical AND operator if and is true,
+// by the logical OR operator otherwise. Nodes of the same kind are flattened.
+func group(builders []*Builder, and bool) *Builder {
+	var children []Node
+	for _, b := range builders {
+		if b == nil {
+			continue
+		}
+		if b.err != nil {
+			return &Builder{err: b.err}
+		}
+		switch n := b.node.(type) {
+		case nil:
+		case *AndNode:
+			if and {
+				children = append(children, n.Children...)
+				continue
+			}
+			children = append(children, n)
+		case *OrNode:
+			if !and {
+				children = append(children, n.Children...)
+				continue
+			}
+			children = append(children, n)
+		default:
+			children = append(children, n)
+		}
+	}
+	switch {
+	case len(children) == 0:
+		return &Builder{}
+	case len(children) == 1:
+		return &Builder{node: children[0]}
+	case and:
+		return &Builder{node: &AndNode{Children: children}}
+	}
+	return &Builder{node: &OrNode{Children: children}}
+}
+
+// Node returns the syntax tree of the query.
+func (b *Builder) Node() (Node, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.node, nil
+}
+
+// Build returns the query as rsql string, see Format.
+func (b *Builder) Build() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	return Format(b.node), nil
+}
+
+// String returns the query as rsql string. If the query
+// is invalid, an empty string is returned, see Build.
+func (b *Builder) String() string {
+	s, _ := b.Build()
+	return s
+}
+
+// Process returns the query processed by the given parser, see Parser.Process.
+func (b *Builder) Process(parser *Parser, options ...func(*ProcessOptions) error) (string, error) {
+	s, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+	return parser.Process(s, options...)
+}
+
+// Cmp returns a builder for a comparison using the given operator, e.g. a custom one like =custom=.
+// Slices are written as lists, other values as described in Eq. Asterisks are only escaped
+// for the operators == and !=.
+func Cmp(key, operator string, value interface{}) *Builder {
+	v := reflect.ValueOf(value)
+	if value != nil && v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).Interface()
+		}
+		return listBuilder(key, operator, values, false)
+	}
+	return singleBuilder(key, operator, value, operator == "==" || operator == "!=")
+}
+
+// Eq returns a builder for an equality comparison (==). Strings are quoted and escaped,
+// asterisks are escaped as well, so they are not handled as wildcards (use Like for patterns). Numbers, booleans and nil
+// are written as literals, times in RFC 3339 format and values of type Raw as given.
+func Eq(key string, value interface{}) *Builder {
+	return singleBuilder(key, "==", value, true)
+}
+
+// Ne returns a builder for an inequality comparison (!=), see Eq.
+func Ne(key string, value interface{}) *Builder {
+	return singleBuilder(key, "!=", value, true)
+}
+
+// Gt returns a builder for a greater than comparison (=gt=).
+func Gt(key string, value interface{}) *Builder {
+	return singleBuilder(key, "=gt=", value, false)
+}
+
+// Ge returns a builder for a greater than or equal comparison (=ge=).
+func Ge(key string, value interface{}) *Builder {
+	return singleBuilder(key, "=ge=", value, false)
+}
+
+// Lt returns a builder for a less than comparison (=lt=).
+func Lt(key string, value interface{}) *Builder {
+	return singleBuilder(key, "=lt=", value, false)
+}
+
+// Le returns a builder for a less than or equal comparison (=le=).
+func Le(key string, value interface{}) *Builder {
+	return singleBuilder(key, "=le=", value, false)
+}
+
+// In returns a builder for an =in= comparison.
+func In(key string, values ...interface{}) *Builder {
+	return listBuilder(key, "=in=", values, false)
+}
+
+// Out returns a builder for an =out= comparison.
+func Out(key string, values ...interface{}) *Builder {
+	return listBuilder(key, "=out=", values, false)
+}
+
+// Like returns a builder for a =like= comparison, the pattern
+// may contain wildcards (*), escaped asterisks (\*) match literal ones.
+func Like(key, pattern string) *Builder {
+	return singleBuilder(key, "=like=", Raw(quote(pattern, `"`)), false)
+}
+
+// ILike returns a builder for an =ilike= comparison, see Like.
+func ILike(key, pattern string) *Builder {
+	return singleBuilder(key, "=ilike=", Raw(quote(pattern, `"`)), false)
+}
+
+// Regex returns a builder for a =regex= comparison.
+func Regex(key, pattern string) *Builder {
+	return singleBuilder(key, "=regex=", Raw(`"`+pattern+`"`), false)
+}
+
+// IsNull returns a builder for a =null= comparison.
+func IsNull(key string, null bool) *Builder {
+	return singleBuilder(key, "=null=", null, false)
+}
+
+// Exists returns a builder for an =exists= comparison.
+func Exists(key string, exists bool) *Builder {
+	return singleBuilder(key, "=exists=", exists, false)
+}
+
+// IsEmpty returns a builder for an =isempty= comparison.
+func IsEmpty(key string, empty bool) *Builder {
+	return singleBuilder(key, "=isempty=", empty, false)
+}
+
+// Between returns a builder for a =between= comparison.
+func Between(key string, low, high interface{}) *Builder {
+	return listBuilder(key, "=between=", []interface{}{low, high}, false)
+}
+
+// NotBetween returns a builder for a =notbetween= comparison.
+func NotBetween(key string, low, high interface{}) *Builder {
+	return listBuilder(key, "=notbetween=", []interface{}{low, high}, false)
+}
+
+// Elem returns a builder for an =elem= comparison, matching arrays
+// containing an element which matches the given query.
+func Elem(key string, query *Builder) *Builder {
+	s, err := query.Build()
+	if err != nil {
+		return &Builder{err: err}
+	}
+	if s == "" {
+		return &Builder{err: fmt.Errorf("missing query for key '%s'", key)}
+	}
+	return singleBuilder(key, "=elem=", Raw("("+s+")"), false)
+}
+
+// Size returns a builder for a =size= comparison.
+func Size(key string, size int) *Builder {
+	return singleBuilder(key, "=size=", size, false)
+}
+
+// All returns a builder for an =all= comparison.
+func All(key string, values ...interface{}) *Builder {
+	return listBuilder(key, "=all=", values, false)
+}
+
+// Near returns a builder for a =near= comparison using the given longitude,
+// latitude and optionally the maximum and minimum distance in meters.
+func Near(key string, lng, lat float64, distances ...float64) *Builder {
+	values := []interface{}{lng, lat}
+	for _, d := range distances {
+		values = append(values, d)
+	}
+	return listBuilder(key, "=near=", values, false)
+}
+
+// Within returns a builder for a =within= comparison using
+// the given points of a polygon, given as longitude and latitude.
+func Within(key string, coordinates ...float64) *Builder {
+	return listBuilder(key, "=within=", floats(coordinates), false)
+}
+
+// Intersects returns a builder for an =intersects= comparison using
+// the given points of a geometry, given as longitude and latitude.
+func Intersects(key string, coordinates ...float64) *Builder {
+	return listBuilder(key, "=intersects=", floats(coordinates), false)
+}
+
+// floats returns the given numbers as values.
+func floats(numbers []float64) []interface{} {
+	values := make([]interface{}, len(numbers))
+	for i, n := range numbers {
+		values[i] = n
+	}
+	return values
+}
+
+// singleBuilder returns a builder for a comparison with a single value.
+// Asterisks within strings are escaped if escape is true.
+func singleBuilder(key, operator string, value interface{}, escape bool) *Builder {
+	s, err := literalValue(value, escape)
+	if err != nil {
+		return &Builder{err: fmt.Errorf("invalid value for key '%s': %w", key, err)}
+	}
+	return comparisonBuilder(key, operator, s)
+}
+
+// listBuilder returns a builder for a comparison with a list of values.
+// Asterisks within strings are escaped if escape is true.
+func listBuilder(key, operator string, values []interface{}, escape bool) *Builder {
+	if len(values) == 0 {
+		return &Builder{err: fmt.Errorf("missing values for key '%s'", key)}
+	}
+	items := make([]string, len(values))
+	for i, v := range values {
+		s, err := literalValue(v, escape)
+		if err != nil {
+			return &Builder{err: fmt.Errorf("invalid value for key '%s': %w", key, err)}
+		}
+		items[i] = s
+	}
+	return comparisonBuilder(key, operator, joinValues(items, true))
+}
+
+// comparisonBuilder returns a builder for the given comparison.
+// An error is returned if the comparison cannot be parsed as given.
+func comparisonBuilder(key, operator, value string) *Builder {
+	node, err := Parse(key + operator + value)
+	if n, ok := node.(*ComparisonNode); err != nil || !ok || n.Key != key || n.Operator != operator || n.Value != value {
+		return &Builder{err: fmt.Errorf("comparison of key '%s' using operator '%s' with value %s cannot be written as rsql", key, operator, value)}
+	}
+	return &Builder{node: node}
+}
+
+// literalValue returns the given value as rsql value.
+// Asterisks within strings are escaped if escape is true.
+func literalValue(value interface{}, escape bool) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case Raw:
+		return string(v), nil
+	case string:
+		return quoteString(v, escape), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return quoteString(v.String(), escape), nil
+	}
+	return "", fmt.Errorf("unsupported value of type %T", value)
+}
+
+// formatFloat returns the given float as rsql value.
+func formatFloat(f float64, bitSize int) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("unsupported number %v", f)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize), nil
+}
+
+// quoteString returns the given string in double quotes, escaping backslashes
+// and double quotes. Asterisks are escaped if wildcards is true.
+func quoteString(s string, wildcards bool) string {
+	if wildcards {
+		return quote(s, `\"*`)
+	}
+	return quote(s, `\"`)
+}
+
+// quote returns the given string in double quotes,
+// escaping the given characters using backslashes.
+func quote(s, escape string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if strings.ContainsRune(escape, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
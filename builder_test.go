@@ -0,0 +1,106 @@
+package rsql
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       *Builder
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", b: And(), want: ``},
+		{name: "eq", b: Eq("status", "A"), want: `status=="A"`},
+		{name: "eq escaped", b: Eq("name", `Jo* "the" \ Doe`), want: `name=="Jo\* \"the\" \\ Doe"`},
+		{name: "eq values", b: And(Eq("a", 1), Eq("b", -2.5), Eq("c", true), Eq("d", nil), Eq("e", uint8(3))), want: `a==1;b==-2.5;c==true;d==null;e==3`},
+		{name: "eq time", b: Eq("at", time.Date(2021, 8, 1, 10, 0, 0, 0, time.UTC)), want: `at==2021-08-01T10:00:00Z`},
+		{name: "eq raw", b: Gt("at", Raw("now()")), want: `at=gt=now()`},
+		{name: "chained", b: Eq("status", "A").And(Lt("qty", 30)).Or(IsNull("qty", true)), want: `status=="A";qty=lt=30,qty=null=true`},
+		{name: "or within and", b: Eq("a", 1).And(Eq("b", 2).Or(Eq("c", 3)), Eq("d", 4)), want: `a==1;(b==2,c==3);d==4`},
+		{name: "flattened", b: Or(Eq("a", 1).Or(Eq("b", 2)), Or(Eq("c", 3))), want: `a==1,b==2,c==3`},
+		{name: "operators", b: And(
+			Ne("a", "x*"), Gt("a", 1), Ge("a", 1), Lt("a", 1), Le("a", 1),
+			In("a", "x", 1), Out("a", "y*"),
+			Like("a", `Jo*\*`), ILike("a", `"jo"*`), Regex("a", `^\d+(a|b)$`),
+			IsNull("a", false), Exists("a", true), IsEmpty("a", false),
+			Between("a", 1, 10), NotBetween("a", 1.5, 10),
+		), want: `a!="x\*";a=gt=1;a=ge=1;a=lt=1;a=le=1;a=in=("x",1);a=out=("y*");a=like="Jo*\*";a=ilike="\"jo\"*";a=regex="^\d+(a|b)$";a=null=false;a=exists=true;a=isempty=false;a=between=(1,10);a=notbetween=(1.5,10)`},
+		{name: "array operators", b: And(
+			Elem("items", Gt("qty", 5).And(Eq("sku", "X"))), Size("tags", 2), All("tags", "a", "b"),
+		), want: `items=elem=(qty=gt=5;sku=="X");tags=size=2;tags=all=("a","b")`},
+		{name: "geo operators", b: And(
+			Near("loc", 7.44, 46.95, 1000), Within("loc", 7, 46, 8, 46, 8, 47), Intersects("loc", 7.5, 46.5),
+		), want: `loc=near=(7.44,46.95,1000);loc=within=(7,46,8,46,8,47);loc=intersects=(7.5,46.5)`},
+		{name: "custom", b: And(Cmp("a", "=custom=", "x*"), Cmp("b", "=custom=", []int{1, 2}), Cmp("c", "==", "x*")), want: `a=custom="x*";b=custom=(1,2);c=="x\*"`},
+		{name: "invalid separator", b: Eq("a", "x,y"), wantErr: true},
+		{name: "invalid key", b: Eq("a=b", 1), wantErr: true},
+		{name: "invalid operator", b: Cmp("a", "=x", 1), wantErr: true},
+		{name: "invalid value", b: Eq("a", struct{}{}), wantErr: true},
+		{name: "invalid number", b: Eq("a", 1.0).Or(Gt("b", 0.0).And(Lt("b", math.Inf(1)))), wantErr: true},
+		{name: "missing values", b: In("a"), wantErr: true},
+		{name: "missing sub-query", b: Elem("a", And()), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.b.Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Build() got = %v, want %v", got, tt.want)
+			}
+			if got != tt.b.String() {
+				t.Errorf("String() got = %v, want %v", tt.b.String(), got)
+			}
+			if node, err := tt.b.Node(); err == nil && Format(node) != got {
+				t.Errorf("Node() got = %v, want %v", Format(node), got)
+			}
+		})
+	}
+}
+
+func TestBuilder_Process(t *testing.T) {
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	tests := []struct {
+		name    string
+		b       *Builder
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "escaped",
+			b:    Eq("name", `Jo* "the" \ Doe`).And(Lt("qty", 30)),
+			want: `{ "$and": [ { "name": "Jo* \"the\" \\ Doe" }, { "qty": { "$lt": 30 } } ] }`,
+		},
+		{
+			name: "like",
+			b:    Like("name", `Jo*\*`),
+			want: `{ "name": { "$regex": "^Jo.*\\*$" } }`,
+		},
+		{
+			name:    "not allowed",
+			b:       Eq("secret", 1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.b.Process(parser, SetForbiddenKeys([]string{"secret"}))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Process() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package rsql
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CacheStats contains statistics about the parser's cache.
+type CacheStats struct {
+	// Hits is the number of queries served from the cache.
+	Hits uint64
+	// Misses is the number of queries which were not found in the cache.
+	Misses uint64
+	// Size is the number of cached queries.
+	Size int
+}
+
+// cache is a size-bounded cache which evicts the least recently used entries.
+// It is safe for concurrent use.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// lru contains the entries, the most recently used first
+	lru    *list.List
+	hits   uint64
+	misses uint64
+}
+
+// cacheEntry is an entry of the cache.
+type cacheEntry struct {
+	key string
+	res string
+	err error
+}
+
+// newCache returns a new cache for the given number of entries.
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// cacheKey returns the cache key for the given string and options.
+// The options are encoded as json, so keys and operators containing
+// separators cannot collide. Maps are encoded sorted by key.
+func cacheKey(s string, opts *ProcessOptions) string {
+	b, _ := json.Marshal(struct {
+		AllowedKeys      []string
+		ForbiddenKeys    []string
+		AllowedOperators map[string][]string
+		MaxLength        int
+		MaxDepth         int
+		MaxComparisons   int
+		MaxListItems     int
+		MaxDistinctKeys  int
+		CollectErrors    bool
+	}{
+		opts.allowedKeys,
+		opts.forbiddenKeys,
+		opts.allowedOperators,
+		opts.maxLength,
+		opts.maxDepth,
+		opts.maxComparisons,
+		opts.maxListItems,
+		opts.maxDistinctKeys,
+		opts.collectErrors,
+	})
+	return fmt.Sprintf("%d:%s%s", len(s), s, b)
+}
+
+// get returns the cached entry for the given key.
+// The second return value is false if the key is not cached.
+func (c *cache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(el)
+	return el.Value.(*cacheEntry), true
+}
+
+// add adds the given result to the cache,
+// evicting the least recently used entry if needed.
+func (c *cache) add(key, res string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		// added concurrently
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, res: res, err: err})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// stats returns the statistics of the cache.
+func (c *cache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Size:   c.lru.Len(),
+	}
+}
+
+// CacheStats returns the statistics of the parser's cache.
+// If caching is not enabled, empty statistics are returned.
+func (parser *Parser) CacheStats() CacheStats {
+	if parser.cache == nil {
+		return CacheStats{}
+	}
+	return parser.cache.stats()
+}
@@ -0,0 +1,173 @@
+package rsql
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func Test_cache(t *testing.T) {
+	c := newCache(2)
+	c.add("a", "1", nil)
+	c.add("b", "2", nil)
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("get() of a failed")
+	}
+	// b is the least recently used entry now
+	c.add("c", "3", fmt.Errorf("error"))
+	if _, ok := c.get("b"); ok {
+		t.Errorf("get() of evicted b succeeded")
+	}
+	entry, ok := c.get("c")
+	if !ok {
+		t.Fatalf("get() of c failed")
+	}
+	if entry.res != "3" || entry.err == nil {
+		t.Errorf("get() of c got = %v, %v, want 3, error", entry.res, entry.err)
+	}
+	want := CacheStats{Hits: 2, Misses: 1, Size: 2}
+	if got := c.stats(); got != want {
+		t.Errorf("stats() got = %+v, want %+v", got, want)
+	}
+}
+
+func Test_cacheKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		s1, s2    string
+		opts1     []func(*ProcessOptions) error
+		opts2     []func(*ProcessOptions) error
+		wantEqual bool
+	}{
+		{
+			name:      "same",
+			s1:        "a==1",
+			s2:        "a==1",
+			opts1:     []func(*ProcessOptions) error{SetAllowedKeys([]string{"a"})},
+			opts2:     []func(*ProcessOptions) error{SetAllowedKeys([]string{"a"})},
+			wantEqual: true,
+		},
+		{
+			name: "different string",
+			s1:   "a==1",
+			s2:   "a==2",
+		},
+		{
+			name:  "different options",
+			s1:    "a==1",
+			s2:    "a==1",
+			opts1: []func(*ProcessOptions) error{SetAllowedKeys([]string{"a"})},
+			opts2: []func(*ProcessOptions) error{SetForbiddenKeys([]string{"a"})},
+		},
+		{
+			name: "same operators",
+			s1:   "a==1",
+			s2:   "a==1",
+			opts1: []func(*ProcessOptions) error{SetAllowedOperators(map[string][]string{
+				"a": {"=="},
+				"b": {"!="},
+			})},
+			opts2: []func(*ProcessOptions) error{SetAllowedOperators(map[string][]string{
+				"b": {"!="},
+				"a": {"=="},
+			})},
+			wantEqual: true,
+		},
+		{
+			name:  "keys containing separators",
+			s1:    "a==1",
+			s2:    "a==1",
+			opts1: []func(*ProcessOptions) error{SetAllowedKeys([]string{"a", "b"})},
+			opts2: []func(*ProcessOptions) error{SetAllowedKeys([]string{"a b"})},
+		},
+		{
+			name: "operators containing separators",
+			s1:   "a==1",
+			s2:   "a==1",
+			opts1: []func(*ProcessOptions) error{SetAllowedOperators(map[string][]string{
+				"a": {"==", "!="},
+			})},
+			opts2: []func(*ProcessOptions) error{SetAllowedOperators(map[string][]string{
+				"a": {"== !="},
+			})},
+		},
+		{
+			name:  "different limits",
+			s1:    "a==1",
+			s2:    "a==1",
+			opts1: []func(*ProcessOptions) error{SetMaxDepth(1)},
+			opts2: []func(*ProcessOptions) error{SetMaxComparisons(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts1, opts2 ProcessOptions
+			for _, op := range tt.opts1 {
+				_ = op(&opts1)
+			}
+			for _, op := range tt.opts2 {
+				_ = op(&opts2)
+			}
+			k1, k2 := cacheKey(tt.s1, &opts1), cacheKey(tt.s2, &opts2)
+			if (k1 == k2) != tt.wantEqual {
+				t.Errorf("cacheKey() got %q and %q, want equal %v", k1, k2, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessCache(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithCache(10))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s := fmt.Sprintf("a==%d", j%5)
+				res, err := parser.Process(s)
+				if err != nil {
+					t.Errorf("Process() error = %v", err)
+					return
+				}
+				if want := fmt.Sprintf(`{ "a": %d }`, j%5); res != want {
+					t.Errorf("Process() got = %v, want %v", res, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// errors are cached as well
+	for i := 0; i < 2; i++ {
+		if _, err := parser.Process("a==1", SetAllowedKeys([]string{"b"})); err == nil {
+			t.Errorf("Process() expected error")
+		}
+	}
+	stats := parser.CacheStats()
+	if stats.Hits+stats.Misses != 1002 {
+		t.Errorf("CacheStats() got %+v, want 1002 lookups", stats)
+	}
+	if stats.Size != 6 {
+		t.Errorf("CacheStats() got size %v, want 6", stats.Size)
+	}
+	if stats.Hits < 1002-6*10 {
+		t.Errorf("CacheStats() got %v hits, want at least %v", stats.Hits, 1002-6*10)
+	}
+}
+
+func TestParser_ProcessCacheAllowedKeys(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithCache(10))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	if _, err := parser.Process("a==1", SetAllowedKeys([]string{"a", "b"})); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if _, err := parser.Process("a==1", SetAllowedKeys([]string{"a b"})); err == nil {
+		t.Errorf("Process() expected error for key which is not allowed")
+	}
+}
@@ -0,0 +1,247 @@
+// Command rsql translates, validates, formats and evaluates rsql queries.
+//
+// Usage:
+//
+//	rsql translate [-to mongo|sql] [-schema file] query
+//	rsql check [-schema file] query
+//	rsql fmt query
+//	rsql eval [-schema file] [-input file] query
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rbicker/go-rsql"
+)
+
+// exit codes
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+const usage = `usage: rsql <command> [flags] query
+
+commands:
+  translate  print the query translated to mongodb or sql
+  check      validate the query and print all errors
+  fmt        print the query in canonical form
+  eval       print the json documents matching the query
+`
+
+// Schema defines the keys and operators allowed within queries.
+// If fields are defined, the allowed keys and operators refer to the backend keys.
+type Schema struct {
+	AllowedKeys      []string            `json:"allowedKeys"`
+	ForbiddenKeys    []string            `json:"forbiddenKeys"`
+	AllowedOperators map[string][]string `json:"allowedOperators"`
+	// Fields maps the keys used in queries to backend keys, see rsql.FieldMap.
+	Fields  map[string]string `json:"fields"`
+	Aliases map[string]string `json:"aliases"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command given by args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	flags := flag.NewFlagSet("rsql "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	to := flags.String("to", "mongo", "translation target, mongo or sql")
+	schemaFile := flags.String("schema", "", "json file defining the allowed keys and operators")
+	input := flags.String("input", "-", "file containing json documents to evaluate, - for stdin")
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	query := flags.Arg(0)
+	backend := rsql.Mongo()
+	switch *to {
+	case "mongo":
+	case "sql":
+		backend = rsql.SQL()
+	default:
+		fmt.Fprintf(stderr, "unknown translation target '%s'\n", *to)
+		return exitUsage
+	}
+	parserOpts := []func(*rsql.Parser) error{backend}
+	var processOpts []func(*rsql.ProcessOptions) error
+	if *schemaFile != "" {
+		schema, err := readSchema(*schemaFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "error while reading schema: %s\n", err)
+			return exitUsage
+		}
+		if schema.Fields != nil {
+			parserOpts = append(parserOpts, rsql.WithFieldMapper(rsql.FieldMap{Fields: schema.Fields, Aliases: schema.Aliases}))
+		}
+		processOpts = append(processOpts,
+			rsql.SetAllowedKeys(schema.AllowedKeys),
+			rsql.SetForbiddenKeys(schema.ForbiddenKeys),
+			rsql.SetAllowedOperators(schema.AllowedOperators),
+		)
+	}
+	parser, err := rsql.NewParser(parserOpts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "error while creating parser: %s\n", err)
+		return exitUsage
+	}
+	switch args[0] {
+	case "translate":
+		res, err := parser.Process(query, processOpts...)
+		if err != nil {
+			printErrors(stderr, query, err)
+			return exitInvalid
+		}
+		fmt.Fprintln(stdout, res)
+	case "check":
+		_, err := parser.Process(query, append(processOpts, rsql.SetCollectErrors(true))...)
+		if err != nil {
+			printErrors(stdout, query, err)
+			return exitInvalid
+		}
+		fmt.Fprintln(stdout, "ok")
+	case "fmt":
+		node, err := rsql.Parse(query)
+		if err != nil {
+			printErrors(stderr, query, err)
+			return exitInvalid
+		}
+		// whitespace around keys and values is removed, as for most backends it is not relevant
+		node, _ = rsql.Rewrite(node, trimComparison)
+		fmt.Fprintln(stdout, rsql.Format(node))
+	case "eval":
+		q, err := parser.Compile(query, processOpts...)
+		if err != nil {
+			printErrors(stderr, query, err)
+			return exitInvalid
+		}
+		r := stdin
+		if *input != "-" {
+			f, err := os.Open(*input)
+			if err != nil {
+				fmt.Fprintf(stderr, "error while opening input: %s\n", err)
+				return exitUsage
+			}
+			defer f.Close()
+			r = f
+		}
+		if err := eval(q, r, stdout); err != nil {
+			fmt.Fprintf(stderr, "error while evaluating: %s\n", err)
+			return exitInvalid
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown command '%s'\n", args[0])
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// readSchema reads the schema from the given json file.
+func readSchema(name string) (*Schema, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var schema Schema
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// printErrors prints the given errors, marking their positions within the query.
+func printErrors(w io.Writer, query string, err error) {
+	var errs rsql.ErrorList
+	var e *rsql.Error
+	switch {
+	case errors.As(err, &errs):
+	case errors.As(err, &e):
+		errs = rsql.ErrorList{e}
+	default:
+		fmt.Fprintln(w, err)
+		return
+	}
+	for _, e := range errs {
+		pos := e.Pos
+		if pos > len(query) {
+			pos = len(query)
+		}
+		fmt.Fprintln(w, query)
+		fmt.Fprintf(w, "%s^ %s (position %d)\n", strings.Repeat(" ", utf8.RuneCountInString(query[:pos])), e.Msg, e.Pos)
+	}
+}
+
+// trimComparison removes whitespace around the key and value of comparisons.
+func trimComparison(node rsql.Node) (rsql.Node, error) {
+	if n, ok := node.(*rsql.ComparisonNode); ok {
+		n.Key = strings.TrimSpace(n.Key)
+		n.Value = strings.TrimSpace(n.Value)
+	}
+	return node, nil
+}
+
+// eval writes the json documents read from r, which match the query, to w.
+// The documents can be given as json array or as newline delimited json.
+func eval(q *rsql.Query, r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	enc := json.NewEncoder(w)
+	// check if the documents are given as array
+	array := false
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			break
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			br.ReadByte()
+			continue
+		}
+		if b[0] == '[' {
+			array = true
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+		}
+		break
+	}
+	for array && dec.More() || !array {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF && !array {
+				return nil
+			}
+			return err
+		}
+		ok, err := q.Match(doc)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := enc.Encode(doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.json")
+	err := os.WriteFile(schema, []byte(`{
+		"allowedKeys": [ "status", "quantity" ],
+		"allowedOperators": { "status": [ "==", "=in=" ] },
+		"fields": { "status": "status", "qty": "quantity", "quantity": "quantity" }
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("error while writing schema: %s", err)
+	}
+	docs := `{ "status": "A", "qty": 5 }
+{ "status": "B", "qty": 50 }
+{ "status": "A", "qty": 25, "tags": [ "x" ] }
+`
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:     "usage",
+			wantCode: exitUsage,
+		},
+		{
+			name:       "translate mongo",
+			args:       []string{"translate", `status=="A";qty=gt=5`},
+			wantStdout: `{ "$and": [ { "status": "A" }, { "qty": { "$gt": 5 } } ] }` + "\n",
+		},
+		{
+			name:       "translate sql",
+			args:       []string{"translate", "-to", "sql", `status=="A";qty=gt=5`},
+			wantStdout: `("status" = 'A' AND "qty" > 5)` + "\n",
+		},
+		{
+			name:       "translate with schema",
+			args:       []string{"translate", "-schema", schema, `qty=gt=5`},
+			wantStdout: `{ "quantity": { "$gt": 5 } }` + "\n",
+		},
+		{
+			name:     "unknown target",
+			args:     []string{"translate", "-to", "xml", `a==1`},
+			wantCode: exitUsage,
+		},
+		{
+			name:       "check ok",
+			args:       []string{"check", "-schema", schema, `status=in=(A,B)`},
+			wantStdout: "ok\n",
+		},
+		{
+			name:     "check errors",
+			args:     []string{"check", "-schema", schema, `status=gt=A;stauts==B`},
+			wantCode: exitInvalid,
+			wantStdout: "status=gt=A;stauts==B\n" +
+				"      ^ operator '=gt=' is not allowed for key 'status', allowed operators are: == =in= (position 6)\n" +
+				"status=gt=A;stauts==B\n" +
+				"            ^ given key 'stauts' is not allowed: unknown field 'stauts' (position 12)\n",
+		},
+		{
+			name:       "fmt",
+			args:       []string{"fmt", `((a==1;b==2));(c==3,d==4)`},
+			wantStdout: "a==1;b==2;(c==3,d==4)\n",
+		},
+		{
+			name:       "fmt whitespace",
+			args:       []string{"fmt", ` a == 1 ; b =in=( 1 , 2 ) `},
+			wantStdout: "a==1;b=in=( 1 , 2 )\n",
+		},
+		{
+			name:     "fmt syntax error",
+			args:     []string{"fmt", `a==1;(b`},
+			wantCode: exitInvalid,
+		},
+		{
+			name:       "eval ndjson",
+			args:       []string{"eval", `status==A;qty=lt=30`},
+			stdin:      docs,
+			wantStdout: `{"qty":5,"status":"A"}` + "\n" + `{"qty":25,"status":"A","tags":["x"]}` + "\n",
+		},
+		{
+			name:       "eval array",
+			args:       []string{"eval", `tags==x`},
+			stdin:      `[ { "tags": [ "x" ] }, { "tags": [ ] } ]`,
+			wantStdout: `{"tags":["x"]}` + "\n",
+		},
+		{
+			name:     "eval invalid json",
+			args:     []string{"eval", `a==1`},
+			stdin:    `{ "a": `,
+			wantCode: exitInvalid,
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"foo", `a==1`},
+			wantCode: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run() code = %d, want %d, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if got := stdout.String(); got != tt.wantStdout {
+				t.Errorf("run() stdout = %q, want %q", got, tt.wantStdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package rsql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// regexes to match date literals
+var (
+	// date without time, e.g. 2021-08-01
+	reDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	// timestamp, e.g. 2021-08-01T10:00:00Z
+	reTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T`)
+	// relative expression, e.g. now-7d or startOfDay+PT8H
+	reRelative = regexp.MustCompile(`^(now|startOfDay|startOfWeek|startOfMonth|startOfYear)((?:[+-][0-9A-Za-z.]+)*)$`)
+	// offset within a relative expression, e.g. -7d
+	reOffset = regexp.MustCompile(`[+-](\d+[smhdwMy]|P[0-9A-Z.]+)`)
+	// ISO 8601 duration, e.g. P1DT12H
+	reISODuration = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+)
+
+// DateOptions defines how date literals are resolved.
+type DateOptions struct {
+	// Now returns the current time, relative expressions
+	// are resolved against it. If nil, time.Now is used.
+	Now func() time.Time
+	// Location is the time zone for dates without time zone
+	// and relative expressions like startOfDay. If nil, UTC is used.
+	Location *time.Location
+}
+
+// WithDates enables the recognition of date literals within values.
+// Unquoted values are recognized as dates if they are RFC 3339 timestamps
+// (2021-08-01T10:00:00+02:00), dates (2021-08-01) or relative expressions.
+// Relative expressions start with now, startOfDay, startOfWeek, startOfMonth
+// or startOfYear and can be followed by offsets like -7d, +1M or -P1DT12H.
+// Units of short offsets are s, m, h, d, w, M (months) and y.
+// Dates are formatted using the native date type of the database.
+// Values of the pattern operators =regex=, =like= and =ilike= are not recognized as dates.
+func WithDates(opts DateOptions) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if opts.Now == nil {
+			opts.Now = time.Now
+		}
+		if opts.Location == nil {
+			opts.Location = time.UTC
+		}
+		parser.dates = &opts
+		return nil
+	}
+}
+
+// resolve resolves the given value to a time. The returned relative
+// value states if the result depends on the current time,
+// ok is false if the value is not a date literal.
+func (opts *DateOptions) resolve(value string) (t time.Time, relative, ok bool, err error) {
+	switch {
+	case reDate.MatchString(value):
+		t, err = time.ParseInLocation("2006-01-02", value, opts.Location)
+		if err != nil {
+			return t, false, true, fmt.Errorf("invalid date '%s'", value)
+		}
+		return t, false, true, nil
+	case reTimestamp.MatchString(value):
+		t, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return t, false, true, fmt.Errorf("invalid timestamp '%s', timestamps need to be formatted according to RFC 3339", value)
+		}
+		return t, false, true, nil
+	}
+	m := reRelative.FindStringSubmatch(value)
+	if m == nil {
+		return t, false, false, nil
+	}
+	t = opts.Now().In(opts.Location)
+	y, mon, d := t.Date()
+	switch m[1] {
+	case "startOfDay":
+		t = time.Date(y, mon, d, 0, 0, 0, 0, opts.Location)
+	case "startOfWeek":
+		// weeks start on monday
+		offset := (int(t.Weekday()) + 6) % 7
+		t = time.Date(y, mon, d-offset, 0, 0, 0, 0, opts.Location)
+	case "startOfMonth":
+		t = time.Date(y, mon, 1, 0, 0, 0, 0, opts.Location)
+	case "startOfYear":
+		t = time.Date(y, time.January, 1, 0, 0, 0, 0, opts.Location)
+	}
+	offsets := m[2]
+	locs := reOffset.FindAllStringIndex(offsets, -1)
+	// the offsets need to be matched completely
+	end := 0
+	for _, loc := range locs {
+		if loc[0] != end {
+			break
+		}
+		end = loc[1]
+	}
+	if end != len(offsets) {
+		return t, true, true, fmt.Errorf("invalid offset in relative date '%s'", value)
+	}
+	for _, loc := range locs {
+		t, err = addOffset(t, offsets[loc[0]:loc[1]])
+		if err != nil {
+			return t, true, true, fmt.Errorf("invalid offset in relative date '%s': %w", value, err)
+		}
+	}
+	return t, true, true, nil
+}
+
+// addOffset adds the given offset like +7d or -P1DT12H to the given time.
+func addOffset(t time.Time, offset string) (time.Time, error) {
+	sign := 1
+	if offset[0] == '-' {
+		sign = -1
+	}
+	offset = offset[1:]
+	if offset[0] == 'P' {
+		return addISODuration(t, offset, sign)
+	}
+	n, err := strconv.Atoi(offset[:len(offset)-1])
+	if err != nil {
+		return t, err
+	}
+	n *= sign
+	switch offset[len(offset)-1] {
+	case 's':
+		return t.Add(time.Duration(n) * time.Second), nil
+	case 'm':
+		return t.Add(time.Duration(n) * time.Minute), nil
+	case 'h':
+		return t.Add(time.Duration(n) * time.Hour), nil
+	case 'd':
+		return t.AddDate(0, 0, n), nil
+	case 'w':
+		return t.AddDate(0, 0, 7*n), nil
+	case 'M':
+		return t.AddDate(0, n, 0), nil
+	case 'y':
+		return t.AddDate(n, 0, 0), nil
+	}
+	return t, fmt.Errorf("unknown unit in '%s'", offset)
+}
+
+// addISODuration adds the given ISO 8601 duration like P1DT12H to the given time.
+func addISODuration(t time.Time, duration string, sign int) (time.Time, error) {
+	m := reISODuration.FindStringSubmatch(duration)
+	if m == nil || duration == "P" || duration[len(duration)-1] == 'T' {
+		return t, fmt.Errorf("invalid duration '%s'", duration)
+	}
+	n := make([]int, 6)
+	for i := range n {
+		if m[i+1] != "" {
+			n[i], _ = strconv.Atoi(m[i+1])
+		}
+	}
+	var seconds float64
+	if m[7] != "" {
+		seconds, _ = strconv.ParseFloat(m[7], 64)
+	}
+	t = t.AddDate(sign*n[0], sign*n[1], sign*(7*n[2]+n[3]))
+	d := time.Duration(n[4])*time.Hour + time.Duration(n[5])*time.Minute + time.Duration(seconds*float64(time.Second))
+	return t.Add(time.Duration(sign) * d), nil
+}
+
+// checkRange checks that the lower bound of the =between= and =notbetween= operators
+// is not after the upper bound, if both bounds are dates. Numeric bounds are checked by rangeValidator.
+func (opts *DateOptions) checkRange(operator string, values []string) error {
+	if operator != "=between=" && operator != "=notbetween=" || len(values) != 2 {
+		return nil
+	}
+	low, _, lowOk, lowErr := opts.resolve(values[0])
+	high, _, highOk, highErr := opts.resolve(values[1])
+	if lowOk && highOk && lowErr == nil && highErr == nil && low.After(high) {
+		return fmt.Errorf("lower bound %s of operator '%s' is greater than upper bound %s", values[0], operator, values[1])
+	}
+	return nil
+}
+
+// formatDate formats the given time using the parser's date formatter.
+func (parser *Parser) formatDate(t time.Time) string {
+	if parser.dateFormatter == nil {
+		return strconv.Quote(t.UTC().Format(time.RFC3339Nano))
+	}
+	return parser.dateFormatter(t)
+}
@@ -0,0 +1,180 @@
+package rsql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateOptions_resolve(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	opts := DateOptions{
+		// wednesday
+		Now: func() time.Time {
+			return time.Date(2021, 8, 4, 15, 30, 0, 0, time.UTC)
+		},
+		Location: cet,
+	}
+	tests := []struct {
+		value        string
+		want         time.Time
+		wantRelative bool
+		wantOk       bool
+		wantErr      bool
+	}{
+		{value: "1"},
+		{value: "'2021-08-01'"},
+		{value: "nowhere"},
+		{value: "2021-08-01", want: time.Date(2021, 8, 1, 0, 0, 0, 0, cet), wantOk: true},
+		{value: "2021-08-01T10:00:00Z", want: time.Date(2021, 8, 1, 10, 0, 0, 0, time.UTC), wantOk: true},
+		{value: "2021-08-01T10:00:00.5+02:00", want: time.Date(2021, 8, 1, 8, 0, 0, 5e8, time.UTC), wantOk: true},
+		{value: "2021-13-01", wantOk: true, wantErr: true},
+		{value: "2021-08-01T10:00", wantOk: true, wantErr: true},
+		{value: "now", want: time.Date(2021, 8, 4, 15, 30, 0, 0, time.UTC), wantRelative: true, wantOk: true},
+		{value: "now-7d", want: time.Date(2021, 7, 28, 15, 30, 0, 0, time.UTC), wantRelative: true, wantOk: true},
+		{value: "now+1h-30m", want: time.Date(2021, 8, 4, 16, 0, 0, 0, time.UTC), wantRelative: true, wantOk: true},
+		{value: "now-1M", want: time.Date(2021, 7, 4, 15, 30, 0, 0, time.UTC), wantRelative: true, wantOk: true},
+		{value: "now-P1DT12H", want: time.Date(2021, 8, 3, 3, 30, 0, 0, time.UTC), wantRelative: true, wantOk: true},
+		{value: "now-P1Y2M", want: time.Date(2020, 6, 4, 15, 30, 0, 0, time.UTC), wantRelative: true, wantOk: true},
+		{value: "now+PT1.5S", want: time.Date(2021, 8, 4, 15, 30, 1, 5e8, time.UTC), wantRelative: true, wantOk: true},
+		{value: "startOfDay", want: time.Date(2021, 8, 4, 0, 0, 0, 0, cet), wantRelative: true, wantOk: true},
+		{value: "startOfDay-1d", want: time.Date(2021, 8, 3, 0, 0, 0, 0, cet), wantRelative: true, wantOk: true},
+		{value: "startOfWeek", want: time.Date(2021, 8, 2, 0, 0, 0, 0, cet), wantRelative: true, wantOk: true},
+		{value: "startOfMonth", want: time.Date(2021, 8, 1, 0, 0, 0, 0, cet), wantRelative: true, wantOk: true},
+		{value: "startOfYear+P2W", want: time.Date(2021, 1, 15, 0, 0, 0, 0, cet), wantRelative: true, wantOk: true},
+		{value: "now-7x", wantRelative: true, wantOk: true, wantErr: true},
+		{value: "now-P", wantRelative: true, wantOk: true, wantErr: true},
+		{value: "now-PT", wantRelative: true, wantOk: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, relative, ok, err := opts.resolve(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if ok != tt.wantOk || relative != tt.wantRelative {
+				t.Errorf("resolve() ok = %v, relative = %v, want %v, %v", ok, relative, tt.wantOk, tt.wantRelative)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("resolve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessDates(t *testing.T) {
+	now := time.Date(2021, 8, 4, 15, 30, 0, 0, time.UTC)
+	dates := WithDates(DateOptions{
+		Now: func() time.Time {
+			return now
+		},
+	})
+	tests := []struct {
+		name    string
+		backend func(*Parser) error
+		s       string
+		want    string
+	}{
+		{
+			name:    "mongo",
+			backend: Mongo(),
+			s:       `createdAt=gt=now-1d;createdAt=lt=2021-08-04T12:00:00+02:00`,
+			want:    `{ "$and": [ { "createdAt": { "$gt": { "$date": "2021-08-03T15:30:00.000Z" } } }, { "createdAt": { "$lt": { "$date": "2021-08-04T10:00:00.000Z" } } } ] }`,
+		},
+		{
+			name:    "mongo list",
+			backend: Mongo(),
+			s:       `day=between=(2021-08-01,startOfDay)`,
+			want:    `{ "day": { "$gte": { "$date": "2021-08-01T00:00:00.000Z" }, "$lte": { "$date": "2021-08-04T00:00:00.000Z" } } }`,
+		},
+		{
+			name:    "quoted values are strings",
+			backend: Mongo(),
+			s:       `name=="now"`,
+			want:    `{ "name": "now" }`,
+		},
+		{
+			name:    "sql",
+			backend: SQL(),
+			s:       `createdAt=ge=startOfMonth`,
+			want:    `"createdAt" >= '2021-08-01T00:00:00Z'`,
+		},
+		{
+			name:    "mongo patterns",
+			backend: Mongo(),
+			s:       `a=regex=2021-08-01;b=like=2021-08-01;c=ilike=now`,
+			want:    `{ "$and": [ { "a": { "$regex": "2021-08-01" } }, { "b": { "$regex": "^2021-08-01$" } }, { "c": { "$regex": "^now$", "$options": "i" } } ] }`,
+		},
+		{
+			name:    "sql patterns",
+			backend: SQL(),
+			s:       `a=regex=2021-08-01;b=like=2021-08-01;c=ilike=now`,
+			want:    `("a" ~ '2021-08-01' AND "b" LIKE '2021-08-01' ESCAPE '\' AND LOWER("c") LIKE LOWER('now') ESCAPE '\')`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.backend, dates)
+			if err != nil {
+				t.Fatalf("error while creating parser: %s", err)
+			}
+			got, err := parser.Process(tt.s)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Process() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessDatesCache(t *testing.T) {
+	now := time.Date(2021, 8, 4, 15, 30, 0, 0, time.UTC)
+	parser, err := NewParser(Mongo(), WithCache(10), WithDates(DateOptions{
+		Now: func() time.Time {
+			return now
+		},
+	}))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	first, _ := parser.Process("a=gt=now;b=gt=2021-08-01")
+	now = now.Add(time.Hour)
+	second, _ := parser.Process("a=gt=now;b=gt=2021-08-01")
+	if first == second {
+		t.Errorf("Process() returned cached result %v for relative date", second)
+	}
+	parser.Process("b=gt=2021-08-01")
+	parser.Process("b=gt=2021-08-01")
+	if stats := parser.CacheStats(); stats.Hits != 1 || stats.Size != 1 {
+		t.Errorf("CacheStats() got = %+v, want 1 hit and size 1", stats)
+	}
+}
+
+func TestParser_ProcessDatesRange(t *testing.T) {
+	tests := []struct {
+		s       string
+		wantErr bool
+	}{
+		{s: `a=between=(2023-01-01,2024-01-01)`},
+		{s: `a=between=(2024-01-01,2023-01-01)`, wantErr: true},
+		{s: `a=notbetween=(now,now-1d)`, wantErr: true},
+		{s: `a=between=(startOfDay,now)`},
+		{s: `a=between=(2024-01-01,x)`},
+	}
+	for _, backend := range []func(*Parser) error{Mongo(), SQL()} {
+		parser, err := NewParser(backend, WithDates(DateOptions{}))
+		if err != nil {
+			t.Fatalf("error while creating parser: %s", err)
+		}
+		for _, tt := range tests {
+			if _, err := parser.Process(tt.s); (err != nil) != tt.wantErr {
+				t.Errorf("Process(%s) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if _, err := parser.Compile(tt.s); (err != nil) != tt.wantErr {
+				t.Errorf("Compile(%s) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+		}
+	}
+}
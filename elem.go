@@ -0,0 +1,73 @@
+package rsql
+
+import "strings"
+
+// parseElem parses the sub-query of the given comparison using the =elem= operator.
+// Errors are added to the state.
+func (parser *Parser) parseElem(n *ComparisonNode, c *comparison, opts *ProcessOptions, state *processState) (Node, bool) {
+	if len(c.value) < 3 || !strings.HasPrefix(c.value, "(") || !strings.HasSuffix(c.value, ")") {
+		state.report(newError(n.valuePos(), "value of operator '=elem=' must be a query in parentheses, e.g. (qty=gt=5;sku==X)"))
+		return nil, false
+	}
+	parsing := parseState{
+		maxDepth: opts.maxDepth,
+	}
+	node := parse(c.value[1:len(c.value)-1], n.valuePos()+1, len(state.scopes)+1, &parsing)
+	state.errs = append(state.errs, parsing.errs...)
+	state.aborted = state.aborted || parsing.aborted
+	return node, node != nil && len(parsing.errs) == 0
+}
+
+// elemMatcher matches arrays containing an element which matches the sub-query.
+type elemMatcher struct {
+	key string
+	sub matcher
+}
+
+func (m *elemMatcher) match(doc interface{}) (bool, error) {
+	value, _ := lookup(doc, m.key)
+	arr, ok := value.([]interface{})
+	if !ok {
+		return false, nil
+	}
+	for _, e := range arr {
+		ok, err := m.sub.match(e)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchSize matches arrays with the number of elements given as argument.
+func matchSize(value interface{}, found bool, args []interface{}) (bool, error) {
+	arg, err := singleArg(args)
+	if err != nil {
+		return false, err
+	}
+	arr, ok := value.([]interface{})
+	if !ok {
+		return false, nil
+	}
+	size, _ := toFloat(arg)
+	return float64(len(arr)) == size, nil
+}
+
+// matchAll matches arrays containing all of the arguments.
+// A single value is treated like an array with one element.
+func matchAll(value interface{}, found bool, args []interface{}) (bool, error) {
+	if !found {
+		return false, nil
+	}
+	elements, ok := value.([]interface{})
+	if !ok {
+		elements = []interface{}{value}
+	}
+	for _, arg := range args {
+		ok, _ := matchIn(arg, true, elements)
+		if !ok {
+			return false, nil
+		}
+	}
+	return len(args) > 0, nil
+}
@@ -0,0 +1,182 @@
+package rsql
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParser_ProcessElem(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend func(*Parser) error
+		options []func(*ProcessOptions) error
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "mongo",
+			backend: Mongo(),
+			s:       `items=elem=(qty=gt=5;sku==X)`,
+			want:    `{ "items": { "$elemMatch": { "$and": [ { "qty": { "$gt": 5 } }, { "sku": X } ] } } }`,
+		},
+		{
+			name:    "mongo nested",
+			backend: Mongo(),
+			s:       `a==1;items=elem=(sku==X,parts=elem=(n=in=(1,2)))`,
+			want:    `{ "$and": [ { "a": 1 }, { "items": { "$elemMatch": { "$or": [ { "sku": X }, { "parts": { "$elemMatch": { "n": { "$in": 1,2 } } } } ] } } } ] }`,
+		},
+		{
+			name:    "mongo size and all",
+			backend: Mongo(),
+			s:       `tags=size=2;tags=all=("a","b")`,
+			want:    `{ "$and": [ { "tags": { "$size": 2 } }, { "tags": { "$all": [ "a","b" ] } } ] }`,
+		},
+		{
+			name:    "sql",
+			backend: SQL(),
+			s:       `items=elem=(qty=gt=5;sku==X;info.ok==true)`,
+			want:    `EXISTS (SELECT 1 FROM jsonb_array_elements("items") AS "elem" WHERE (("elem" #>> '{"qty"}')::numeric > 5 AND ("elem" #>> '{"sku"}') = 'X' AND ("elem" #>> '{"info","ok"}')::boolean = TRUE))`,
+		},
+		{
+			name:    "sql nested",
+			backend: SQL(),
+			s:       `items=elem=(parts=elem=(n=in=(1,2)))`,
+			want:    `EXISTS (SELECT 1 FROM jsonb_array_elements("items") AS "elem" WHERE EXISTS (SELECT 1 FROM jsonb_array_elements(("elem" #> '{"parts"}')) AS "elem" WHERE ("elem" #>> '{"n"}')::numeric IN (1, 2)))`,
+		},
+		{
+			name:    "sql size and all",
+			backend: SQL(),
+			s:       `tags=size=2;tags=all=(a,1)`,
+			want:    `(jsonb_array_length("tags") = 2 AND "tags" @> jsonb_build_array('a', 1))`,
+		},
+		{
+			name:    "allowed keys",
+			backend: Mongo(),
+			options: []func(*ProcessOptions) error{SetAllowedKeys([]string{"items", "items.qty"})},
+			s:       `items=elem=(qty=gt=5)`,
+			want:    `{ "items": { "$elemMatch": { "qty": { "$gt": 5 } } } }`,
+		},
+		{
+			name:    "key not allowed",
+			backend: Mongo(),
+			options: []func(*ProcessOptions) error{SetAllowedKeys([]string{"items", "items.qty"})},
+			s:       `items=elem=(qty=gt=5;sku==X)`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid sub-query",
+			backend: Mongo(),
+			s:       `items=elem=(qty=gt=5;sku)`,
+			wantErr: true,
+		},
+		{
+			name:    "missing parentheses",
+			backend: Mongo(),
+			s:       `items=elem=qty`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid size",
+			backend: Mongo(),
+			s:       `tags=size=-1`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.backend)
+			if err != nil {
+				t.Fatalf("error while creating parser: %s", err)
+			}
+			got, err := parser.Process(tt.s, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Process() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessElemKeys(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithFieldMapper(FieldMap{
+		Fields: map[string]string{
+			"items":     "lineItems",
+			"items.qty": "lineItems.quantity",
+			"other":     "other",
+		},
+		Aliases: map[string]string{
+			"items.amount": "items.qty",
+		},
+	}))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	got, err := parser.Process(`items=elem=(amount=gt=5)`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if want := `{ "lineItems": { "$elemMatch": { "quantity": { "$gt": 5 } } } }`; got != want {
+		t.Errorf("Process() got = %v, want %v", got, want)
+	}
+	_, err = parser.Process(`items=elem=(sku==X)`)
+	if !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Process() error = %v, want %v", err, ErrUnknownField)
+	}
+	// positions are relative to the whole query
+	var e *Error
+	_, err = parser.Process(`other==1;items=elem=(qty=gt=1;x=)`)
+	if !errors.As(err, &e) || e.Pos != 31 {
+		t.Errorf("Process() error = %v, want error at position 31", err)
+	}
+}
+
+func TestParser_MatchElem(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"tags": [ "a", "b", "c" ],
+		"single": "a",
+		"items": [ { "sku": "X", "qty": 2 }, { "sku": "Y", "qty": 8, "parts": [ { "n": 1 } ] } ]
+	}`), &doc)
+	if err != nil {
+		t.Fatalf("error while unmarshalling document: %s", err)
+	}
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: `items=elem=(sku==Y;qty=gt=5)`, want: true},
+		{s: `items=elem=(sku==X;qty=gt=5)`, want: false},
+		{s: `items=elem=(parts=elem=(n==1))`, want: true},
+		{s: `single=elem=(a==1)`, want: false},
+		{s: `tags=size=3`, want: true},
+		{s: `tags=size=2`, want: false},
+		{s: `single=size=1`, want: false},
+		{s: `tags=all=(a,c)`, want: true},
+		{s: `tags=all=(a,d)`, want: false},
+		{s: `single=all=(a)`, want: true},
+		{s: `missing=all=(a)`, want: false},
+	}
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			q, err := parser.Compile(tt.s)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := q.Match(doc)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package rsql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Error describes a problem within a query.
+type Error struct {
+	// Pos is the position (byte offset) of the problem within the query.
+	Pos int
+	// Msg describes the problem.
+	Msg string
+	// Err is the underlying error, if any.
+	Err error
+	// Suggestions contains similar, valid alternatives
+	// for an unknown key or operator, the most similar first.
+	Suggestions []string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (position %d)", e.Msg, e.Pos)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError returns a new Error for the given position.
+// If the last argument is an error, it is used as the underlying error.
+func newError(pos int, format string, a ...interface{}) *Error {
+	err := fmt.Errorf(format, a...)
+	return &Error{
+		Pos: pos,
+		Msg: err.Error(),
+		Err: unwrapOne(err),
+	}
+}
+
+// unwrapOne returns the error wrapped by err, if any.
+func unwrapOne(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// ErrorList is a list of errors found within a query.
+type ErrorList []*Error
+
+// Error implements the error interface.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(l), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors in the list.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// sortByPos sorts the list by the position of its errors.
+func (l ErrorList) sortByPos() {
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Pos < l[j].Pos
+	})
+}
@@ -0,0 +1,51 @@
+package rsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownField is returned by FieldMap if a key cannot be resolved.
+var ErrUnknownField = errors.New("unknown field")
+
+// FieldMapper resolves the keys used in rsql statements to backend keys.
+// If a key cannot be resolved, an error should be returned,
+// which causes the key to be rejected.
+type FieldMapper interface {
+	Resolve(ctx context.Context, key string) (backendKey string, err error)
+}
+
+// FieldMapperFunc is an adapter to use ordinary functions as FieldMapper.
+type FieldMapperFunc func(ctx context.Context, key string) (string, error)
+
+// Resolve calls f(ctx, key).
+func (f FieldMapperFunc) Resolve(ctx context.Context, key string) (string, error) {
+	return f(ctx, key)
+}
+
+// FieldMap is a simple, map based FieldMapper.
+// Keys which are neither defined in Fields nor in Aliases are rejected.
+type FieldMap struct {
+	// Fields maps field names to backend keys.
+	// If the backend key is empty, the field name is used as is.
+	Fields map[string]string
+	// Aliases maps alternative names to field names defined in Fields.
+	Aliases map[string]string
+}
+
+// Resolve returns the backend key for the given key.
+func (m FieldMap) Resolve(_ context.Context, key string) (string, error) {
+	field := key
+	if alias, ok := m.Aliases[key]; ok {
+		field = alias
+	}
+	backendKey, ok := m.Fields[field]
+	if !ok {
+		return "", fmt.Errorf("%w '%s'", ErrUnknownField, key)
+	}
+	if backendKey == "" {
+		return field, nil
+	}
+	return backendKey, nil
+}
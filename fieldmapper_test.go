@@ -0,0 +1,67 @@
+package rsql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFieldMap_Resolve(t *testing.T) {
+	m := FieldMap{
+		Fields: map[string]string{
+			"createdAt": "created_at",
+			"name":      "",
+		},
+		Aliases: map[string]string{
+			"created": "createdAt",
+			"title":   "name",
+		},
+	}
+	tests := []struct {
+		name    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "field",
+			key:  "createdAt",
+			want: "created_at",
+		},
+		{
+			name: "field without backend key",
+			key:  "name",
+			want: "name",
+		},
+		{
+			name: "alias",
+			key:  "created",
+			want: "created_at",
+		},
+		{
+			name: "alias to field without backend key",
+			key:  "title",
+			want: "name",
+		},
+		{
+			name:    "unknown",
+			key:     "created_at",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Resolve(context.Background(), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !errors.Is(err, ErrUnknownField) {
+				t.Errorf("Resolve() error = %v, want ErrUnknownField", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package rsql
+
+import (
+	"strings"
+)
+
+// Format returns the given node as rsql string in canonical form.
+// Parentheses are only added where needed, nested groups of the same kind
+// are flattened (as they are by Parse) and groups with a single child are
+// replaced by the child, so parsing the result of a parsed query returns the same tree.
+// Keys and values are written as given, as whitespace within them is significant.
+// Formatting a nil Node results in an empty string.
+func Format(node Node) string {
+	var b strings.Builder
+	format(&b, unwrap(node))
+	return b.String()
+}
+
+// format writes the given node to b.
+func format(b *strings.Builder, node Node) {
+	switch n := node.(type) {
+	case *AndNode:
+		formatGroup(b, n.Children, true)
+	case *OrNode:
+		formatGroup(b, n.Children, false)
+	case *ComparisonNode:
+		b.WriteString(n.Key)
+		b.WriteString(n.Operator)
+		b.WriteString(n.Value)
+	}
+}
+
+// formatGroup writes the given children of an AND node if and is true,
+// of an OR node otherwise, to b. ORs within ANDs are enclosed in parentheses.
+func formatGroup(b *strings.Builder, children []Node, and bool) {
+	sep := ","
+	if and {
+		sep = ";"
+	}
+	for i, child := range groupChildren(children, and) {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		if _, ok := child.(*OrNode); ok && and {
+			b.WriteString("(")
+			format(b, child)
+			b.WriteString(")")
+			continue
+		}
+		format(b, child)
+	}
+}
+
+// groupChildren returns the given, unwrapped children of an AND node if and is true,
+// of an OR node otherwise. Nested groups of the same kind are flattened, empty ones removed.
+func groupChildren(children []Node, and bool) []Node {
+	res := make([]Node, 0, len(children))
+	for _, child := range children {
+		switch n := unwrap(child).(type) {
+		case nil:
+		case *AndNode:
+			if and {
+				res = append(res, groupChildren(n.Children, and)...)
+				continue
+			}
+			res = append(res, n)
+		case *OrNode:
+			if !and {
+				res = append(res, groupChildren(n.Children, and)...)
+				continue
+			}
+			res = append(res, n)
+		default:
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// unwrap returns the only child of AND and OR nodes having a single non-empty child
+// and nil for empty nodes. Other nodes are returned as they are.
+func unwrap(node Node) Node {
+	var children []Node
+	switch n := node.(type) {
+	case *AndNode:
+		children = n.Children
+	case *OrNode:
+		children = n.Children
+	case nil:
+		return nil
+	default:
+		return node
+	}
+	var res Node
+	for _, child := range children {
+		if child = unwrap(child); child == nil {
+			continue
+		}
+		if res != nil {
+			return node
+		}
+		res = child
+	}
+	return res
+}
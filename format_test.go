@@ -0,0 +1,194 @@
+package rsql
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+		want string
+	}{
+		{
+			name: "nil",
+			node: nil,
+			want: "",
+		},
+		{
+			name: "whitespace",
+			node: &ComparisonNode{Key: " a ", Operator: "==", Value: ` "x y" `},
+			want: ` a == "x y" `,
+		},
+		{
+			name: "list",
+			node: &ComparisonNode{Key: "a", Operator: "=in=", Value: "( 1, 2 ,3)"},
+			want: "a=in=( 1, 2 ,3)",
+		},
+		{
+			name: "sub-query",
+			node: &ComparisonNode{Key: "items", Operator: "=elem=", Value: "( (qty=gt=5) ; sku=in=( X, Y) )"},
+			want: "items=elem=( (qty=gt=5) ; sku=in=( X, Y) )",
+		},
+		{
+			name: "or within and",
+			node: &AndNode{Children: []Node{
+				&ComparisonNode{Key: "a", Operator: "==", Value: "1"},
+				&OrNode{Children: []Node{
+					&ComparisonNode{Key: "b", Operator: "==", Value: "2"},
+					&ComparisonNode{Key: "c", Operator: "==", Value: "3"},
+				}},
+			}},
+			want: "a==1;(b==2,c==3)",
+		},
+		{
+			name: "and within or",
+			node: &OrNode{Children: []Node{
+				&AndNode{Children: []Node{
+					&ComparisonNode{Key: "a", Operator: "==", Value: "1"},
+					&ComparisonNode{Key: "b", Operator: "==", Value: "2"},
+				}},
+				&ComparisonNode{Key: "c", Operator: "==", Value: "3"},
+			}},
+			want: "a==1;b==2,c==3",
+		},
+		{
+			name: "nested groups of the same kind",
+			node: &AndNode{Children: []Node{
+				&AndNode{Children: []Node{
+					&ComparisonNode{Key: "a", Operator: "==", Value: "1"},
+					&ComparisonNode{Key: "b", Operator: "==", Value: "2"},
+				}},
+				&OrNode{Children: []Node{
+					&OrNode{Children: []Node{
+						&ComparisonNode{Key: "c", Operator: "==", Value: "3"},
+						&ComparisonNode{Key: "d", Operator: "==", Value: "4"},
+					}},
+					&ComparisonNode{Key: "e", Operator: "==", Value: "5"},
+				}},
+			}},
+			want: "a==1;b==2;(c==3,d==4,e==5)",
+		},
+		{
+			name: "single and empty children",
+			node: &AndNode{Children: []Node{
+				&OrNode{},
+				&OrNode{Children: []Node{
+					&AndNode{Children: []Node{
+						&ComparisonNode{Key: "a", Operator: "==", Value: "1"},
+						&ComparisonNode{Key: "b", Operator: "==", Value: "2"},
+					}},
+				}},
+				&ComparisonNode{Key: "c", Operator: "==", Value: "3"},
+			}},
+			want: "a==1;b==2;c==3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.node); got != tt.want {
+				t.Errorf("Format() = %v, want %v", got, tt.want)
+			}
+			if tt.node != nil && tt.node.String() != tt.want {
+				t.Errorf("String() = %v, want %v", tt.node.String(), tt.want)
+			}
+		})
+	}
+}
+
+// strip returns a copy of the given node without positions.
+func strip(node Node) Node {
+	switch n := node.(type) {
+	case *AndNode:
+		res := &AndNode{}
+		for _, child := range n.Children {
+			res.Children = append(res.Children, strip(child))
+		}
+		return res
+	case *OrNode:
+		res := &OrNode{}
+		for _, child := range n.Children {
+			res.Children = append(res.Children, strip(child))
+		}
+		return res
+	case *ComparisonNode:
+		return &ComparisonNode{Key: n.Key, Operator: n.Operator, Value: n.Value}
+	}
+	return node
+}
+
+// checkRoundTrip checks if parsing the formatted syntax tree of s results in the same syntax tree.
+func checkRoundTrip(t *testing.T, s string) {
+	t.Helper()
+	node, err := Parse(s)
+	if err != nil {
+		return
+	}
+	formatted := Format(node)
+	got, err := Parse(formatted)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v, formatted from %q", formatted, err, s)
+	}
+	if !reflect.DeepEqual(strip(got), strip(node)) {
+		t.Fatalf("Parse(Format(Parse(%q))) = %#v, want %#v", s, strip(got), strip(node))
+	}
+	if again := Format(got); again != formatted {
+		t.Fatalf("Format() not stable for %q: %q != %q", s, again, formatted)
+	}
+}
+
+// randomQuery returns a random, syntactically valid query.
+func randomQuery(r *rand.Rand, depth int) string {
+	if depth <= 0 || r.Intn(3) == 0 {
+		keys := []string{"a", "b", "status", "address.city", " c ", "items"}
+		operators := []string{"==", "!=", "=gt=", "=le=", "=in=", "=out=", "=like=", "=custom="}
+		values := []string{"1", "-2.5", "A", `"x y"`, `'it''s'`, "Jo*", `\*`, " 3 ", "(1,2)", "( A , 'b c' )", "true", "null", "2021-08-01T10:00:00Z"}
+		key := keys[r.Intn(len(keys))]
+		if key == "items" {
+			return key + "=elem=(" + randomQuery(r, depth-1) + ")"
+		}
+		return key + operators[r.Intn(len(operators))] + values[r.Intn(len(values))]
+	}
+	parts := make([]string, 2+r.Intn(2))
+	for i := range parts {
+		parts[i] = randomQuery(r, depth-1)
+		if r.Intn(2) == 0 {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	if r.Intn(2) == 0 {
+		return strings.Join(parts, ";")
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		s := randomQuery(r, 4)
+		if _, err := Parse(s); err != nil {
+			t.Fatalf("randomQuery() returned invalid query %q: %v", s, err)
+		}
+		checkRoundTrip(t, s)
+	}
+}
+
+func FuzzFormat(f *testing.F) {
+	for _, s := range []string{
+		`a==1`,
+		` a == 1 `,
+		`a=in=( 1, 2 )`,
+		`(a==1;b==2),c==3`,
+		`a==1;(b==2,c==3)`,
+		`((a==1;b==2);c==3)`,
+		`((a==1,b==2),c==3);d==4`,
+		`items=elem=((qty=gt=5);sku==X)`,
+		`name=="John Doe";status=='A'`,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(checkRoundTrip)
+}
@@ -0,0 +1,165 @@
+package rsql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// regex to match function calls like ObjectId("xxx")
+var reFunctionCall = regexp.MustCompile(`(?s)^([A-Za-z_][A-Za-z0-9_.]*)\((.*)\)$`)
+
+// Function is a function which can be called within values, e.g. ObjectId("xxx").
+// It receives the evaluated arguments and returns a typed value, which is formatted
+// by the parser's backend. Arguments are strings, int64, float64, bool, nil
+// or the results of nested function calls.
+type Function func(args ...interface{}) (interface{}, error)
+
+// WithFunctions adds functions which can be called within values.
+// As soon as functions are registered, calling an unknown function results in an error.
+// Values of the pattern operators =regex=, =like= and =ilike= are not evaluated.
+// The results of queries containing function calls are never cached,
+// as functions may depend on the current time or other state.
+func WithFunctions(functions map[string]Function) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if parser.functions == nil {
+			parser.functions = make(map[string]Function)
+		}
+		for name, f := range functions {
+			if !reFunctionCall.MatchString(name + "()") {
+				return fmt.Errorf("invalid function name '%s'", name)
+			}
+			if f == nil {
+				return fmt.Errorf("function '%s' must not be nil", name)
+			}
+			parser.functions[name] = f
+		}
+		return nil
+	}
+}
+
+// isFunctionCall checks if the given value is a function call.
+func isFunctionCall(value string) bool {
+	return reFunctionCall.MatchString(value)
+}
+
+// evaluate evaluates the given argument or function call.
+func (parser *Parser) evaluate(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	m := reFunctionCall.FindStringSubmatch(s)
+	if m == nil {
+		return literal(s), nil
+	}
+	name := m[1]
+	f, ok := parser.functions[name]
+	if !ok {
+		var names []string
+		for n := range parser.functions {
+			names = append(names, n)
+		}
+		err := &Error{Msg: fmt.Sprintf("unknown function '%s'", name)}
+		return nil, withSuggestions(err, suggest(name, names))
+	}
+	parts, err := splitArguments(m[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments of function '%s': %w", name, err)
+	}
+	args := make([]interface{}, 0, len(parts))
+	for _, p := range parts {
+		arg, err := parser.evaluate(p)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	res, err := f(args...)
+	if err != nil {
+		return nil, fmt.Errorf("function '%s' failed: %w", name, err)
+	}
+	return res, nil
+}
+
+// literal returns the typed value of the given literal.
+func literal(s string) interface{} {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return unescape(s[1 : len(s)-1])
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if reNumber.MatchString(s) {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	return s
+}
+
+// splitArguments splits the given arguments of a function call at commas,
+// while considering quotes and parentheses.
+func splitArguments(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var res []string
+	var quote rune
+	var par, start int
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			par++
+		case r == ')':
+			par--
+			if par < 0 {
+				return nil, errors.New("parentheses mismatch")
+			}
+		case r == ',' && par == 0:
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("missing closing quote")
+	}
+	if par != 0 {
+		return nil, errors.New("parentheses mismatch")
+	}
+	return append(res, s[start:]), nil
+}
+
+// formatValue formats the given typed value using the parser's formatters.
+func (parser *Parser) formatValue(v interface{}) (string, error) {
+	if t, ok := v.(time.Time); ok {
+		return parser.formatDate(t), nil
+	}
+	if parser.valueFormatter != nil {
+		return parser.valueFormatter(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,213 @@
+package rsql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// objectID is a test type formatted as extended json object id.
+type objectID string
+
+func (id objectID) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"$oid":%q}`, string(id))), nil
+}
+
+func (id objectID) String() string {
+	return string(id)
+}
+
+var reObjectID = regexp.MustCompile(`^[0-9a-f]{24}$`)
+
+var testFunctions = map[string]Function{
+	"ObjectId": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expected exactly one argument")
+		}
+		s, ok := args[0].(string)
+		if !ok || !reObjectID.MatchString(s) {
+			return nil, fmt.Errorf("invalid object id %v", args[0])
+		}
+		return objectID(s), nil
+	},
+	"lower": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("expected exactly one argument")
+		}
+		return strings.ToLower(fmt.Sprint(args[0])), nil
+	},
+	"add": func(args ...interface{}) (interface{}, error) {
+		var sum int64
+		for _, a := range args {
+			i, ok := a.(int64)
+			if !ok {
+				return nil, fmt.Errorf("invalid number %v", a)
+			}
+			sum += i
+		}
+		return sum, nil
+	},
+	"args": func(args ...interface{}) (interface{}, error) {
+		return args, nil
+	},
+}
+
+func TestParser_evaluate(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithFunctions(testFunctions))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	tests := []struct {
+		s       string
+		want    interface{}
+		wantErr bool
+	}{
+		{s: `abc`, want: "abc"},
+		{s: `'a\'b'`, want: "a'b"},
+		{s: `12`, want: int64(12)},
+		{s: `1.5`, want: 1.5},
+		{s: `true`, want: true},
+		{s: `null`, want: nil},
+		{s: `ObjectId("5f2c3f0e8b3c4e1a2b3c4d5e")`, want: objectID("5f2c3f0e8b3c4e1a2b3c4d5e")},
+		{s: `ObjectId("xxx")`, wantErr: true},
+		{s: `lower("A,B")`, want: "a,b"},
+		{s: `add(1, add(2, 3))`, want: int64(6)},
+		{s: `args()`, want: []interface{}{}},
+		{s: `args("a", 'b)', 1)`, want: []interface{}{"a", "b)", int64(1)}},
+		{s: `args("a)`, wantErr: true},
+		{s: `args(a))`, wantErr: true},
+		{s: `lowr("A")`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parser.evaluate(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluate() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessFunctions(t *testing.T) {
+	tests := []struct {
+		name            string
+		backend         func(*Parser) error
+		s               string
+		want            string
+		wantErr         bool
+		wantErrString   string
+		wantSuggestions []string
+	}{
+		{
+			name:    "mongo",
+			backend: Mongo(),
+			s:       `_id==ObjectId("5f2c3f0e8b3c4e1a2b3c4d5e")`,
+			want:    `{ "_id": {"$oid":"5f2c3f0e8b3c4e1a2b3c4d5e"} }`,
+		},
+		{
+			name:    "mongo nested",
+			backend: Mongo(),
+			s:       `name==lower("A,B");n=gt=add(1,add(2,3))`,
+			want:    `{ "$and": [ { "name": "a,b" }, { "n": { "$gt": 6 } } ] }`,
+		},
+		{
+			name:    "sql",
+			backend: SQL(),
+			s:       `_id==ObjectId("5f2c3f0e8b3c4e1a2b3c4d5e");n=gt=add(1,2)`,
+			want:    `("_id" = '5f2c3f0e8b3c4e1a2b3c4d5e' AND "n" > 3)`,
+		},
+		{
+			name:    "invalid argument",
+			backend: Mongo(),
+			s:       `_id==ObjectId("xxx")`,
+			wantErr: true,
+		},
+		{
+			name:            "unknown function",
+			backend:         Mongo(),
+			s:               `name==lowr("A")`,
+			wantErr:         true,
+			wantErrString:   "invalid value for key 'name': unknown function 'lowr', did you mean 'lower'? (position 6)",
+			wantSuggestions: []string{"lower"},
+		},
+		{
+			name:    "patterns are not evaluated",
+			backend: Mongo(),
+			s:       `a=regex=ab(c);b=regex=lower(x);c=like=lower(*)`,
+			want:    `{ "$and": [ { "a": { "$regex": "ab(c)" } }, { "b": { "$regex": "lower(x)" } }, { "c": { "$regex": "^lower\\(.*\\)$" } } ] }`,
+		},
+		{
+			name:    "unsupported sql value",
+			backend: SQL(),
+			s:       `a==args(1)`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.backend, WithFunctions(testFunctions))
+			if err != nil {
+				t.Fatalf("error while creating parser: %s", err)
+			}
+			got, err := parser.Process(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var e *Error
+				if !errors.As(err, &e) {
+					t.Fatalf("Process() error = %v, want *Error", err)
+				}
+				if tt.wantErrString != "" && err.Error() != tt.wantErrString {
+					t.Errorf("Process() error = %v, want %v", err, tt.wantErrString)
+				}
+				if !reflect.DeepEqual(e.Suggestions, tt.wantSuggestions) {
+					t.Errorf("Process() suggestions = %v, want %v", e.Suggestions, tt.wantSuggestions)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Process() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFunctions(t *testing.T) {
+	if _, err := NewParser(WithFunctions(map[string]Function{"1x": testFunctions["lower"]})); err == nil {
+		t.Errorf("WithFunctions() expected error for invalid name")
+	}
+	if _, err := NewParser(WithFunctions(map[string]Function{"x": nil})); err == nil {
+		t.Errorf("WithFunctions() expected error for nil function")
+	}
+	// without functions, calls are passed through
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	got, err := parser.Process(`_id==ObjectId("xxx")`)
+	if err != nil || got != `{ "_id": ObjectId("xxx") }` {
+		t.Errorf("Process() got = %v, %v", got, err)
+	}
+}
+
+func TestParser_CompileFunctionPatterns(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithFunctions(testFunctions))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	q, err := parser.Compile(`name=regex=ab(c);name=like=ab*`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if ok, err := q.Match(map[string]interface{}{"name": "abc"}); !ok || err != nil {
+		t.Errorf("Match() got = %v, %v, want true", ok, err)
+	}
+}
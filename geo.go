@@ -0,0 +1,425 @@
+package rsql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// earthRadius is the radius of the earth in meters, as used by mongodb.
+const earthRadius = 6378100.0
+
+// point is a position given as longitude and latitude.
+type point [2]float64
+
+// geometry is a simplified geojson geometry.
+// Polygons are represented by their outer ring.
+type geometry struct {
+	points   []point
+	lines    [][]point
+	polygons [][]point
+}
+
+// geoValidator returns a value transformer which checks the values
+// of the geospatial operators =near=, =within= and =intersects=.
+// Coordinates are given as flat list of longitudes and latitudes.
+func geoValidator() func(key, operator string, values []string) ([]string, error) {
+	return func(key, operator string, values []string) ([]string, error) {
+		var coordinates []string
+		switch operator {
+		case "=near=":
+			if len(values) < 2 || len(values) > 4 {
+				return nil, fmt.Errorf("operator '%s' requires a list of longitude, latitude and optionally the maximum and minimum distance in meters", operator)
+			}
+			coordinates = values[:2]
+			distances, err := parseNumbers(values[2:])
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range distances {
+				if d < 0 {
+					return nil, fmt.Errorf("distance %v must not be negative", d)
+				}
+			}
+			if len(distances) == 2 && distances[1] > distances[0] {
+				return nil, fmt.Errorf("minimum distance %v is greater than maximum distance %v", distances[1], distances[0])
+			}
+		case "=within=":
+			if len(values) < 6 || len(values)%2 != 0 {
+				return nil, fmt.Errorf("operator '%s' requires a list of at least three points given as longitude and latitude", operator)
+			}
+			coordinates = values
+		case "=intersects=":
+			if len(values) < 2 || len(values)%2 != 0 {
+				return nil, fmt.Errorf("operator '%s' requires a list of points given as longitude and latitude", operator)
+			}
+			coordinates = values
+		default:
+			return values, nil
+		}
+		numbers, err := parseNumbers(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		points := toPoints(numbers)
+		for _, p := range points {
+			if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+				return nil, fmt.Errorf("invalid coordinates %v, %v", p[0], p[1])
+			}
+		}
+		// the closed ring of a polygon needs at least three distinct points
+		if operator == "=within=" && countDistinct(points) < 3 {
+			return nil, fmt.Errorf("operator '%s' requires a list of at least three distinct points", operator)
+		}
+		return values, nil
+	}
+}
+
+// parseNumbers parses the given numeric values.
+func parseNumbers(values []string) ([]float64, error) {
+	res := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !reNumber.MatchString(v) {
+			return nil, fmt.Errorf("'%s' is not a number", v)
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", v)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// toPoints converts a flat list of longitudes and latitudes to points.
+func toPoints(numbers []float64) []point {
+	res := make([]point, 0, len(numbers)/2)
+	for i := 0; i+1 < len(numbers); i += 2 {
+		res = append(res, point{numbers[i], numbers[i+1]})
+	}
+	return res
+}
+
+// closeRing appends the first point to the given ring if it is not closed.
+func closeRing(ring []point) []point {
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		return append(ring, ring[0])
+	}
+	return ring
+}
+
+// countDistinct returns the number of distinct points.
+func countDistinct(points []point) int {
+	seen := make(map[point]bool, len(points))
+	for _, p := range points {
+		seen[p] = true
+	}
+	return len(seen)
+}
+
+// isRing checks if the given points form a closed ring.
+func isRing(points []point) bool {
+	return len(points) >= 4 && points[0] == points[len(points)-1]
+}
+
+// mongoPosition returns the given coordinates as geojson position.
+func mongoPosition(lng, lat string) string {
+	return fmt.Sprintf(`[ %s, %s ]`, lng, lat)
+}
+
+// mongoPositions returns the given flat list of coordinates as geojson positions.
+// The first position is appended if close is true and the positions do not form a ring.
+func mongoPositions(values []string, close bool) string {
+	positions := make([]string, 0, len(values)/2+1)
+	for i := 0; i+1 < len(values); i += 2 {
+		positions = append(positions, mongoPosition(values[i], values[i+1]))
+	}
+	if close && positions[0] != positions[len(positions)-1] {
+		positions = append(positions, positions[0])
+	}
+	return "[ " + strings.Join(positions, ", ") + " ]"
+}
+
+// mongoGeometry returns the given flat list of coordinates as geojson geometry:
+// a point for a single position, a polygon for a closed ring and a line otherwise.
+func mongoGeometry(values []string) string {
+	if len(values) == 2 {
+		return fmt.Sprintf(`{ "type": "Point", "coordinates": %s }`, mongoPosition(values[0], values[1]))
+	}
+	numbers, _ := parseNumbers(values)
+	if isRing(toPoints(numbers)) {
+		return fmt.Sprintf(`{ "type": "Polygon", "coordinates": [ %s ] }`, mongoPositions(values, true))
+	}
+	return fmt.Sprintf(`{ "type": "LineString", "coordinates": %s }`, mongoPositions(values, false))
+}
+
+// argsToNumbers converts the given numeric arguments to floats.
+func argsToNumbers(args []interface{}) ([]float64, error) {
+	res := make([]float64, 0, len(args))
+	for _, arg := range args {
+		f, ok := toFloat(arg)
+		if !ok {
+			return nil, fmt.Errorf("'%v' is not a number", arg)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// argsToGeometry converts the given flat list of coordinates to a geometry
+// like mongoGeometry. If polygon is true, the points always form a polygon.
+func argsToGeometry(args []interface{}, polygon bool) (geometry, error) {
+	numbers, err := argsToNumbers(args)
+	if err != nil {
+		return geometry{}, err
+	}
+	points := toPoints(numbers)
+	switch {
+	case polygon || isRing(points):
+		return geometry{polygons: [][]point{closeRing(points)}}, nil
+	case len(points) == 1:
+		return geometry{points: points}, nil
+	}
+	return geometry{lines: [][]point{points}}, nil
+}
+
+// valueToGeometry converts the given geojson geometry of a document to a geometry.
+// Legacy coordinate pairs (e.g. [ 1, 2 ]) are accepted as points.
+func valueToGeometry(value interface{}) (geometry, bool) {
+	if p, ok := valueToPoint(value); ok {
+		return geometry{points: []point{p}}, true
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return geometry{}, false
+	}
+	coordinates := m["coordinates"]
+	switch m["type"] {
+	case "Point":
+		p, ok := valueToPoint(coordinates)
+		return geometry{points: []point{p}}, ok
+	case "MultiPoint":
+		points, ok := valueToPoints(coordinates)
+		return geometry{points: points}, ok
+	case "LineString":
+		points, ok := valueToPoints(coordinates)
+		return geometry{lines: [][]point{points}}, ok
+	case "Polygon":
+		rings, ok := coordinates.([]interface{})
+		if !ok || len(rings) == 0 {
+			return geometry{}, false
+		}
+		ring, ok := valueToPoints(rings[0])
+		return geometry{polygons: [][]point{ring}}, ok
+	}
+	return geometry{}, false
+}
+
+// valueToPoint converts the given coordinate pair to a point.
+func valueToPoint(value interface{}) (point, bool) {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) != 2 {
+		return point{}, false
+	}
+	lng, lngOk := toFloat(arr[0])
+	lat, latOk := toFloat(arr[1])
+	return point{lng, lat}, lngOk && latOk
+}
+
+// valueToPoints converts the given list of coordinate pairs to points.
+func valueToPoints(value interface{}) ([]point, bool) {
+	arr, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	res := make([]point, 0, len(arr))
+	for _, v := range arr {
+		p, ok := valueToPoint(v)
+		if !ok {
+			return nil, false
+		}
+		res = append(res, p)
+	}
+	return res, true
+}
+
+// vertices returns all points of the geometry.
+func (g geometry) vertices() []point {
+	res := append([]point(nil), g.points...)
+	for _, l := range g.lines {
+		res = append(res, l...)
+	}
+	for _, p := range g.polygons {
+		res = append(res, p...)
+	}
+	return res
+}
+
+// paths returns all lines and rings of the geometry.
+func (g geometry) paths() [][]point {
+	return append(append([][]point(nil), g.lines...), g.polygons...)
+}
+
+// contains checks if the given point is part of the geometry,
+// including the boundary of its polygons.
+func (g geometry) contains(p point) bool {
+	for _, q := range g.points {
+		if p == q {
+			return true
+		}
+	}
+	for _, path := range g.paths() {
+		for i := 1; i < len(path); i++ {
+			if onSegment(path[i-1], path[i], p) {
+				return true
+			}
+		}
+	}
+	for _, ring := range g.polygons {
+		if inRing(ring, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// within checks if the geometry lies within the given geometry.
+func (g geometry) within(other geometry) bool {
+	vertices := g.vertices()
+	for _, p := range vertices {
+		if !other.contains(p) {
+			return false
+		}
+	}
+	return len(vertices) > 0
+}
+
+// intersects checks if the geometry intersects the given geometry.
+func (g geometry) intersects(other geometry) bool {
+	for _, p := range g.vertices() {
+		if other.contains(p) {
+			return true
+		}
+	}
+	for _, p := range other.vertices() {
+		if g.contains(p) {
+			return true
+		}
+	}
+	for _, a := range g.paths() {
+		for _, b := range other.paths() {
+			for i := 1; i < len(a); i++ {
+				for j := 1; j < len(b); j++ {
+					if segmentsIntersect(a[i-1], a[i], b[j-1], b[j]) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// orientation returns the orientation of the given points:
+// 0 if they are collinear, 1 if clockwise and -1 if counterclockwise.
+func orientation(a, b, c point) int {
+	v := (b[1]-a[1])*(c[0]-b[0]) - (b[0]-a[0])*(c[1]-b[1])
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// onSegment checks if the point p lies on the segment from a to b.
+func onSegment(a, b, p point) bool {
+	return orientation(a, b, p) == 0 &&
+		p[0] >= math.Min(a[0], b[0]) && p[0] <= math.Max(a[0], b[0]) &&
+		p[1] >= math.Min(a[1], b[1]) && p[1] <= math.Max(a[1], b[1])
+}
+
+// segmentsIntersect checks if the segment from a to b intersects the segment from c to d.
+func segmentsIntersect(a, b, c, d point) bool {
+	o1, o2 := orientation(a, b, c), orientation(a, b, d)
+	o3, o4 := orientation(c, d, a), orientation(c, d, b)
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+	return onSegment(a, b, c) || onSegment(a, b, d) || onSegment(c, d, a) || onSegment(c, d, b)
+}
+
+// inRing checks if the given point lies within the given ring.
+func inRing(ring []point, p point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// distance returns the distance between the given points in meters.
+func distance(a, b point) float64 {
+	rad := func(deg float64) float64 {
+		return deg * math.Pi / 180
+	}
+	dLat := rad(b[1] - a[1])
+	dLng := rad(b[0] - a[0])
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(a[1]))*math.Cos(rad(b[1]))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// matchNear matches points within the distances given as arguments
+// (longitude, latitude, maximum and minimum distance in meters).
+func matchNear(value interface{}, found bool, args []interface{}) (bool, error) {
+	numbers, err := argsToNumbers(args)
+	if err != nil {
+		return false, err
+	}
+	if len(numbers) < 2 {
+		return false, fmt.Errorf("expected longitude and latitude")
+	}
+	g, ok := valueToGeometry(value)
+	if !ok || len(g.points) == 0 {
+		return false, nil
+	}
+	maxDistance, minDistance := math.Inf(1), 0.0
+	if len(numbers) > 2 {
+		maxDistance = numbers[2]
+	}
+	if len(numbers) > 3 {
+		minDistance = numbers[3]
+	}
+	for _, p := range g.points {
+		d := distance(point{numbers[0], numbers[1]}, p)
+		if d <= maxDistance && d >= minDistance {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchWithin matches geometries within the polygon given as arguments.
+func matchWithin(value interface{}, found bool, args []interface{}) (bool, error) {
+	polygon, err := argsToGeometry(args, true)
+	if err != nil {
+		return false, err
+	}
+	g, ok := valueToGeometry(value)
+	return ok && g.within(polygon), nil
+}
+
+// matchIntersects matches geometries intersecting the geometry given as arguments.
+func matchIntersects(value interface{}, found bool, args []interface{}) (bool, error) {
+	other, err := argsToGeometry(args, false)
+	if err != nil {
+		return false, err
+	}
+	g, ok := valueToGeometry(value)
+	return ok && g.intersects(other), nil
+}
@@ -0,0 +1,120 @@
+package rsql
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParser_ProcessGeo(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			s:    `loc=near=(7.44,46.95,1000)`,
+			want: `{ "loc": { "$near": { "$geometry": { "type": "Point", "coordinates": [ 7.44, 46.95 ] }, "$maxDistance": 1000 } } }`,
+		},
+		{
+			s:    `loc=near=(7.44,46.95,1000,10)`,
+			want: `{ "loc": { "$near": { "$geometry": { "type": "Point", "coordinates": [ 7.44, 46.95 ] }, "$maxDistance": 1000, "$minDistance": 10 } } }`,
+		},
+		{
+			s:    `loc=within=(0,0,10,0,10,10)`,
+			want: `{ "loc": { "$geoWithin": { "$geometry": { "type": "Polygon", "coordinates": [ [ [ 0, 0 ], [ 10, 0 ], [ 10, 10 ], [ 0, 0 ] ] ] } } } }`,
+		},
+		{
+			s:    `loc=intersects=(1,2)`,
+			want: `{ "loc": { "$geoIntersects": { "$geometry": { "type": "Point", "coordinates": [ 1, 2 ] } } } }`,
+		},
+		{
+			s:    `loc=intersects=(0,0,10,10)`,
+			want: `{ "loc": { "$geoIntersects": { "$geometry": { "type": "LineString", "coordinates": [ [ 0, 0 ], [ 10, 10 ] ] } } } }`,
+		},
+		{
+			s:    `loc=intersects=(0,0,10,0,10,10,0,0)`,
+			want: `{ "loc": { "$geoIntersects": { "$geometry": { "type": "Polygon", "coordinates": [ [ [ 0, 0 ], [ 10, 0 ], [ 10, 10 ], [ 0, 0 ] ] ] } } } }`,
+		},
+		{s: `loc=near=(7.44)`, wantErr: true},
+		{s: `loc=near=(200,46.95)`, wantErr: true},
+		{s: `loc=near=(7.44,46.95,-1)`, wantErr: true},
+		{s: `loc=near=(7.44,46.95,10,100)`, wantErr: true},
+		{s: `loc=near=(a,46.95)`, wantErr: true},
+		{s: `loc=within=(0,0,10,0)`, wantErr: true},
+		{s: `loc=within=(0,0,1,0,0,0)`, wantErr: true},
+		{s: `loc=within=(0,0,1,0,1,0,0,0)`, wantErr: true},
+		{s: `loc=intersects=(0,0,10)`, wantErr: true},
+	}
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parser.Process(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Process() got = %v, want %v", got, tt.want)
+			}
+			if got != "" && !json.Valid([]byte(got)) {
+				t.Errorf("Process() got invalid json %v", got)
+			}
+		})
+	}
+}
+
+func TestParser_MatchGeo(t *testing.T) {
+	docs := map[string]string{
+		"point":   `{ "loc": { "type": "Point", "coordinates": [ 7.44, 46.95 ] } }`,
+		"legacy":  `{ "loc": [ 7.44, 46.95 ] }`,
+		"line":    `{ "loc": { "type": "LineString", "coordinates": [ [ -5, 5 ], [ 5, 5 ] ] } }`,
+		"polygon": `{ "loc": { "type": "Polygon", "coordinates": [ [ [ 1, 1 ], [ 3, 1 ], [ 3, 3 ], [ 1, 1 ] ] ] } }`,
+		"missing": `{ "name": "x" }`,
+	}
+	tests := []struct {
+		s    string
+		doc  string
+		want bool
+	}{
+		{s: `loc=near=(7.44,46.95,10)`, doc: "point", want: true},
+		{s: `loc=near=(7.45,46.95,500)`, doc: "point", want: false},
+		{s: `loc=near=(7.45,46.95,1000)`, doc: "legacy", want: true},
+		{s: `loc=near=(7.45,46.95,1000,800)`, doc: "legacy", want: false},
+		{s: `loc=near=(7.45,46.95)`, doc: "missing", want: false},
+		{s: `loc=within=(7,46,8,46,8,47,7,47)`, doc: "point", want: true},
+		{s: `loc=within=(0,0,10,0,10,10,0,10)`, doc: "point", want: false},
+		{s: `loc=within=(0,0,10,0,10,10,0,10)`, doc: "polygon", want: true},
+		{s: `loc=within=(0,0,10,0,10,10,0,10)`, doc: "line", want: false},
+		{s: `loc=intersects=(0,0,10,0,10,10,0,10,0,0)`, doc: "line", want: true},
+		{s: `loc=intersects=(0,-10,0,10)`, doc: "line", want: true},
+		{s: `loc=intersects=(6,-10,6,10)`, doc: "line", want: false},
+		{s: `loc=intersects=(2,2)`, doc: "polygon", want: true},
+		{s: `loc=intersects=(0,0,4,4)`, doc: "polygon", want: true},
+		{s: `loc=intersects=(7.44,46.95)`, doc: "point", want: true},
+	}
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.s+" "+tt.doc, func(t *testing.T) {
+			var doc interface{}
+			if err := json.Unmarshal([]byte(docs[tt.doc]), &doc); err != nil {
+				t.Fatalf("error while unmarshalling document: %s", err)
+			}
+			q, err := parser.Compile(tt.s)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := q.Match(doc)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// Package http provides a net/http middleware, which parses the filter, sort,
+// limit and offset query parameters of requests using an rsql parser.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rbicker/go-rsql"
+)
+
+// Query contains the parsed query parameters of a request.
+type Query struct {
+	// Filter is the processed filter. Multiple filter parameters are combined using AND.
+	// Filter is empty if no filter was given.
+	Filter string
+	// Sort contains the parsed sort parameters.
+	Sort rsql.Sort
+	// Limit is the given or default limit, 0 if there is no limit.
+	// If a max limit is set, Limit is never 0.
+	Limit int
+	// Offset is the given offset, 0 by default.
+	Offset int
+}
+
+// contextKey is the key of the query within the request context.
+type contextKey struct{}
+
+// FromContext returns the query stored in the given context by the middleware.
+func FromContext(ctx context.Context) (*Query, bool) {
+	q, ok := ctx.Value(contextKey{}).(*Query)
+	return q, ok
+}
+
+// Middleware parses query parameters and stores the result in the request context.
+type Middleware struct {
+	parser         *rsql.Parser
+	processOptions []func(*rsql.ProcessOptions) error
+	defaultLimit   int
+	maxLimit       int
+}
+
+// New returns a new middleware using the given parser.
+func New(parser *rsql.Parser, options ...func(*Middleware) error) (*Middleware, error) {
+	if parser == nil {
+		return nil, errors.New("parser must not be nil")
+	}
+	m := &Middleware{
+		parser: parser,
+	}
+	for _, option := range options {
+		if err := option(m); err != nil {
+			return nil, fmt.Errorf("setting middleware option failed: %w", err)
+		}
+	}
+	if m.maxLimit > 0 && m.defaultLimit > m.maxLimit {
+		return nil, fmt.Errorf("default limit %d is greater than max limit %d", m.defaultLimit, m.maxLimit)
+	}
+	return m, nil
+}
+
+// WithProcessOptions sets the process options used for the filter and sort parameters.
+func WithProcessOptions(options ...func(*rsql.ProcessOptions) error) func(m *Middleware) error {
+	return func(m *Middleware) error {
+		m.processOptions = append(m.processOptions, options...)
+		return nil
+	}
+}
+
+// WithDefaultLimit sets the limit used if the request does not contain a limit parameter.
+// If no default limit is set, the max limit is used.
+func WithDefaultLimit(n int) func(m *Middleware) error {
+	return func(m *Middleware) error {
+		if n < 0 {
+			return fmt.Errorf("default limit must not be negative")
+		}
+		m.defaultLimit = n
+		return nil
+	}
+}
+
+// WithMaxLimit sets the maximum value of the limit parameter.
+// If a max limit is set, a limit of 0 (no limit) is rejected.
+func WithMaxLimit(n int) func(m *Middleware) error {
+	return func(m *Middleware) error {
+		if n < 0 {
+			return fmt.Errorf("max limit must not be negative")
+		}
+		m.maxLimit = n
+		return nil
+	}
+}
+
+// Handler returns a handler, which parses the query parameters of requests
+// and passes the result to next within the request context.
+// If the parameters are invalid, an RFC 7807 problem is written instead.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, err := m.Parse(r)
+		if err != nil {
+			var p *Problem
+			if !errors.As(err, &p) {
+				p = &Problem{Detail: err.Error()}
+			}
+			p.Write(w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, q)))
+	})
+}
+
+// Parse parses the query parameters of the given request.
+// Errors are returned as *Problem.
+func (m *Middleware) Parse(r *http.Request) (*Query, error) {
+	params := r.URL.Query()
+	q := &Query{
+		Limit: m.defaultLimit,
+	}
+	if q.Limit == 0 {
+		q.Limit = m.maxLimit
+	}
+	p := &Problem{}
+	// filter, each parameter is processed on its own, so limits apply per parameter
+	var filters []string
+	for i, filter := range params["filter"] {
+		if strings.TrimSpace(filter) == "" {
+			continue
+		}
+		res, err := m.parser.ProcessContext(r.Context(), filter, m.processOptions...)
+		if err != nil {
+			p.add("filter", err, []segment{{end: len(filter), index: i}})
+			continue
+		}
+		filters = append(filters, res)
+	}
+	q.Filter = m.parser.Join(filters...)
+	// sort
+	if sort, segments := combine(params["sort"], ","); sort != "" {
+		res, err := m.parser.ParseSortContext(r.Context(), sort, m.processOptions...)
+		if err != nil {
+			p.add("sort", err, segments)
+		}
+		q.Sort = res
+	}
+	// limit and offset
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		switch {
+		case err != nil || n < 0:
+			p.Errors = append(p.Errors, ProblemError{Param: "limit", Detail: "limit must be a non-negative integer"})
+		case m.maxLimit > 0 && n == 0:
+			p.Errors = append(p.Errors, ProblemError{Param: "limit", Detail: "limit must be greater than 0"})
+		case m.maxLimit > 0 && n > m.maxLimit:
+			p.Errors = append(p.Errors, ProblemError{Param: "limit", Detail: fmt.Sprintf("limit must not be greater than %d", m.maxLimit)})
+		default:
+			q.Limit = n
+		}
+	}
+	if s := params.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			p.Errors = append(p.Errors, ProblemError{Param: "offset", Detail: "offset must be a non-negative integer"})
+		} else {
+			q.Offset = n
+		}
+	}
+	if len(p.Errors) > 0 {
+		return nil, p
+	}
+	return q, nil
+}
+
+// segment is the location of a parameter within a combined string.
+type segment struct {
+	start, end int
+	index      int
+}
+
+// combine combines the given, non-empty parameters using the given separator.
+func combine(values []string, sep string) (string, []segment) {
+	var b strings.Builder
+	var segments []segment
+	for i, v := range values {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		s := segment{start: b.Len(), index: i}
+		b.WriteString(v)
+		s.end = b.Len()
+		segments = append(segments, s)
+	}
+	return b.String(), segments
+}
+
+// locate returns the index of the parameter and the position within
+// the parameter for the given position within the combined string.
+func locate(segments []segment, pos int) (int, int) {
+	for _, s := range segments {
+		if pos < s.end || s == segments[len(segments)-1] {
+			pos -= s.start
+			if pos < 0 {
+				pos = 0
+			}
+			return s.index, pos
+		}
+	}
+	return 0, pos
+}
+
+// Problem is an RFC 7807 problem details object, describing invalid query parameters.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError describes a problem within a query parameter.
+type ProblemError struct {
+	// Param is the name of the query parameter.
+	Param string `json:"param"`
+	// Index is the index of the parameter, if it was given multiple times.
+	Index int `json:"index"`
+	// Position is the position of the problem within the parameter, if known.
+	Position *int `json:"position,omitempty"`
+	// Detail describes the problem.
+	Detail string `json:"detail"`
+	// Suggestions contains valid alternatives for unknown keys or operators.
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Error implements the error interface.
+func (p *Problem) Error() string {
+	msgs := make([]string, 0, len(p.Errors))
+	for _, e := range p.Errors {
+		msg := fmt.Sprintf("%s: %s", e.Param, e.Detail)
+		if e.Position != nil {
+			msg += fmt.Sprintf(" (position %d)", *e.Position)
+		}
+		msgs = append(msgs, msg)
+	}
+	if p.Detail != "" {
+		msgs = append([]string{p.Detail}, msgs...)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add adds the given error of the given parameter.
+func (p *Problem) add(param string, err error, segments []segment) {
+	var errs rsql.ErrorList
+	var e *rsql.Error
+	switch {
+	case errors.As(err, &errs):
+	case errors.As(err, &e):
+		errs = rsql.ErrorList{e}
+	default:
+		index, _ := locate(segments, 0)
+		p.Errors = append(p.Errors, ProblemError{Param: param, Index: index, Detail: err.Error()})
+		return
+	}
+	for _, e := range errs {
+		index, pos := locate(segments, e.Pos)
+		p.Errors = append(p.Errors, ProblemError{
+			Param:       param,
+			Index:       index,
+			Position:    &pos,
+			Detail:      e.Msg,
+			Suggestions: e.Suggestions,
+		})
+	}
+}
+
+// Write writes the problem as application/problem+json response.
+// Missing fields are set to describe a bad request.
+func (p *Problem) Write(w http.ResponseWriter) {
+	if p.Status == 0 {
+		p.Status = http.StatusBadRequest
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Detail == "" {
+		p.Detail = "invalid query parameters"
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,244 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/rbicker/go-rsql"
+)
+
+func TestMiddleware_Handler(t *testing.T) {
+	parser, err := rsql.NewParser(rsql.Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	m, err := New(parser,
+		WithProcessOptions(rsql.SetAllowedKeys([]string{"status", "qty", "name"}), rsql.SetCollectErrors(true)),
+		WithDefaultLimit(10),
+		WithMaxLimit(100),
+	)
+	if err != nil {
+		t.Fatalf("error while creating middleware: %s", err)
+	}
+	tests := []struct {
+		name       string
+		params     url.Values
+		want       *Query
+		wantStatus int
+		wantErrors []ProblemError
+	}{
+		{
+			name:       "empty",
+			params:     url.Values{},
+			want:       &Query{Limit: 10},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "all params",
+			params: url.Values{
+				"filter": {"status==A"},
+				"sort":   {"name,-qty"},
+				"limit":  {"20"},
+				"offset": {"40"},
+			},
+			want: &Query{
+				Filter: `{ "status": A }`,
+				Sort:   rsql.Sort{{Key: "name"}, {Key: "qty", Descending: true}},
+				Limit:  20,
+				Offset: 40,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "multiple filters",
+			params:     url.Values{"filter": {"status==A,status==B", "", "qty=gt=5"}},
+			want:       &Query{Filter: `{ "$and": [ { "$or": [ { "status": A }, { "status": B } ] }, { "qty": { "$gt": 5 } } ] }`, Limit: 10},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "invalid params",
+			params: url.Values{
+				"filter": {"status==A", "qty=gt=5;stauts==B"},
+				"sort":   {"name", "-x"},
+				"limit":  {"1000"},
+				"offset": {"-1"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []ProblemError{
+				{Param: "filter", Index: 1, Position: intPtr(9), Detail: "given key 'stauts' is not allowed, did you mean 'status'?", Suggestions: []string{"status"}},
+				{Param: "sort", Index: 1, Position: intPtr(1), Detail: "given key 'x' is not allowed"},
+				{Param: "limit", Detail: "limit must not be greater than 100"},
+				{Param: "offset", Detail: "offset must be a non-negative integer"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Query
+			handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = FromContext(r.Context())
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?"+tt.params.Encode(), nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Handler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handler() query = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantErrors == nil {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Handler() content type = %s, want application/problem+json", ct)
+			}
+			var p Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("error while unmarshalling problem: %s", err)
+			}
+			if p.Status != http.StatusBadRequest || p.Type != "about:blank" || p.Title != "Bad Request" {
+				t.Errorf("Handler() problem = %+v", p)
+			}
+			if !reflect.DeepEqual(p.Errors, tt.wantErrors) {
+				t.Errorf("Handler() errors = %+v, want %+v", p.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Limits(t *testing.T) {
+	parser, err := rsql.NewParser(rsql.Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	m, err := New(parser, WithProcessOptions(rsql.SetMaxDepth(1), rsql.SetMaxLength(10)))
+	if err != nil {
+		t.Fatalf("error while creating middleware: %s", err)
+	}
+	tests := []struct {
+		name       string
+		params     url.Values
+		want       *Query
+		wantErrors []ProblemError
+	}{
+		{
+			name:   "depth per filter",
+			params: url.Values{"filter": {"(a==1)", "b==2"}},
+			want:   &Query{Filter: `{ "$and": [ { "a": 1 }, { "b": 2 } ] }`},
+		},
+		{
+			name:   "length per filter",
+			params: url.Values{"filter": {"a==1;b==2", "c==3;d==4"}},
+			want:   &Query{Filter: `{ "$and": [ { "$and": [ { "a": 1 }, { "b": 2 } ] }, { "$and": [ { "c": 3 }, { "d": 4 } ] } ] }`},
+		},
+		{
+			name:       "filter too long",
+			params:     url.Values{"filter": {"a==1", "b==2;c==3;d==4"}},
+			wantErrors: []ProblemError{{Param: "filter", Index: 1, Detail: "query exceeds limit: length must not be greater than 10"}},
+		},
+		{
+			name:       "filter too deep",
+			params:     url.Values{"filter": {"a==1", "((b==2))"}},
+			wantErrors: []ProblemError{{Param: "filter", Index: 1, Position: intPtr(2), Detail: "query exceeds limit: depth must not be greater than 1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Parse(httptest.NewRequest(http.MethodGet, "/?"+tt.params.Encode(), nil))
+			if tt.wantErrors != nil {
+				p, ok := err.(*Problem)
+				if !ok {
+					t.Fatalf("Parse() error = %v, want *Problem", err)
+				}
+				if !reflect.DeepEqual(p.Errors, tt.wantErrors) {
+					t.Errorf("Parse() errors = %+v, want %+v", p.Errors, tt.wantErrors)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware_MaxLimit(t *testing.T) {
+	parser, err := rsql.NewParser(rsql.Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	m, err := New(parser, WithMaxLimit(100))
+	if err != nil {
+		t.Fatalf("error while creating middleware: %s", err)
+	}
+	tests := []struct {
+		name       string
+		params     url.Values
+		want       *Query
+		wantErrors []ProblemError
+	}{
+		{
+			name:   "no limit",
+			params: url.Values{},
+			want:   &Query{Limit: 100},
+		},
+		{
+			name:   "limit",
+			params: url.Values{"limit": {"20"}},
+			want:   &Query{Limit: 20},
+		},
+		{
+			name:       "zero limit",
+			params:     url.Values{"limit": {"0"}},
+			wantErrors: []ProblemError{{Param: "limit", Detail: "limit must be greater than 0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Parse(httptest.NewRequest(http.MethodGet, "/?"+tt.params.Encode(), nil))
+			if tt.wantErrors != nil {
+				p, ok := err.(*Problem)
+				if !ok {
+					t.Fatalf("Parse() error = %v, want *Problem", err)
+				}
+				if !reflect.DeepEqual(p.Errors, tt.wantErrors) {
+					t.Errorf("Parse() errors = %+v, want %+v", p.Errors, tt.wantErrors)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	parser, err := rsql.NewParser(rsql.Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	if _, err := New(nil); err == nil {
+		t.Errorf("New() expected error for missing parser")
+	}
+	if _, err := New(parser, WithDefaultLimit(-1)); err == nil {
+		t.Errorf("New() expected error for negative default limit")
+	}
+	if _, err := New(parser, WithDefaultLimit(20), WithMaxLimit(10)); err == nil {
+		t.Errorf("New() expected error for default limit greater than max limit")
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,86 @@
+package rsql
+
+import "fmt"
+
+// names of the limits which can be set using the process options.
+const (
+	LimitLength       = "length"
+	LimitDepth        = "depth"
+	LimitComparisons  = "number of comparisons"
+	LimitListItems    = "number of list items"
+	LimitDistinctKeys = "number of distinct keys"
+)
+
+// LimitError is returned if a query exceeds a limit
+// defined by the process options.
+type LimitError struct {
+	// Limit is the name of the exceeded limit, e.g. LimitDepth.
+	Limit string
+	// Max is the configured maximum.
+	Max int
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("query exceeds limit: %s must not be greater than %d", e.Limit, e.Max)
+}
+
+// SetMaxLength set's the maximum length (in bytes) of the query string.
+// A value of 0 means no limit.
+func SetMaxLength(n int) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		if n < 0 {
+			return fmt.Errorf("%s limit must not be negative", LimitLength)
+		}
+		opts.maxLength = n
+		return nil
+	}
+}
+
+// SetMaxDepth set's the maximum nesting depth of parentheses.
+// A value of 0 means no limit.
+func SetMaxDepth(n int) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		if n < 0 {
+			return fmt.Errorf("%s limit must not be negative", LimitDepth)
+		}
+		opts.maxDepth = n
+		return nil
+	}
+}
+
+// SetMaxComparisons set's the maximum number of comparisons (e.g. a==1) within the query.
+// A value of 0 means no limit.
+func SetMaxComparisons(n int) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		if n < 0 {
+			return fmt.Errorf("%s limit must not be negative", LimitComparisons)
+		}
+		opts.maxComparisons = n
+		return nil
+	}
+}
+
+// SetMaxListItems set's the maximum number of items within a list value, e.g. a=in=(1,2,3).
+// A value of 0 means no limit.
+func SetMaxListItems(n int) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		if n < 0 {
+			return fmt.Errorf("%s limit must not be negative", LimitListItems)
+		}
+		opts.maxListItems = n
+		return nil
+	}
+}
+
+// SetMaxDistinctKeys set's the maximum number of distinct keys within the query.
+// A value of 0 means no limit.
+func SetMaxDistinctKeys(n int) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		if n < 0 {
+			return fmt.Errorf("%s limit must not be negative", LimitDistinctKeys)
+		}
+		opts.maxDistinctKeys = n
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package rsql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParser_ProcessLimits(t *testing.T) {
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	tests := []struct {
+		name      string
+		s         string
+		options   []func(*ProcessOptions) error
+		wantLimit string
+		wantErr   bool
+	}{
+		{
+			name: "within all limits",
+			s:    "a==1;(b=in=(1,2),c==3)",
+			options: []func(*ProcessOptions) error{
+				SetMaxLength(100),
+				SetMaxDepth(1),
+				SetMaxComparisons(3),
+				SetMaxListItems(2),
+				SetMaxDistinctKeys(3),
+			},
+		},
+		{
+			name: "length",
+			s:    "a==1;b==2",
+			options: []func(*ProcessOptions) error{
+				SetMaxLength(8),
+			},
+			wantLimit: LimitLength,
+			wantErr:   true,
+		},
+		{
+			name: "depth",
+			s:    "a==1;(b==1,(c==1;d==1))",
+			options: []func(*ProcessOptions) error{
+				SetMaxDepth(1),
+			},
+			wantLimit: LimitDepth,
+			wantErr:   true,
+		},
+		{
+			name: "deeply nested",
+			s:    strings.Repeat("(", 10000) + "a==1" + strings.Repeat(")", 10000),
+			options: []func(*ProcessOptions) error{
+				SetMaxDepth(10),
+			},
+			wantLimit: LimitDepth,
+			wantErr:   true,
+		},
+		{
+			name: "comparisons",
+			s:    "a==1;(b==1,c==1)",
+			options: []func(*ProcessOptions) error{
+				SetMaxComparisons(2),
+			},
+			wantLimit: LimitComparisons,
+			wantErr:   true,
+		},
+		{
+			name: "list items",
+			s:    "a=in=(1,2,3)",
+			options: []func(*ProcessOptions) error{
+				SetMaxListItems(2),
+			},
+			wantLimit: LimitListItems,
+			wantErr:   true,
+		},
+		{
+			name: "distinct keys",
+			s:    "a==1,a==2,b==1,(c==1;a==3)",
+			options: []func(*ProcessOptions) error{
+				SetMaxDistinctKeys(2),
+			},
+			wantLimit: LimitDistinctKeys,
+			wantErr:   true,
+		},
+		{
+			name: "negative limit",
+			s:    "a==1",
+			options: []func(*ProcessOptions) error{
+				SetMaxDepth(-1),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Process(tt.s, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantLimit == "" {
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Errorf("Process() error = %v, want LimitError", err)
+				return
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Process() exceeded limit = %v, want %v", limitErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
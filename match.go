@@ -0,0 +1,578 @@
+package rsql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MatchFunc reports whether the value of a document's field matches a comparison.
+// If the document does not contain the field, value is nil and found is false.
+// Args contains the typed values of the comparison: strings, int64, float64, bool, nil,
+// time.Time for dates, *regexp.Regexp for patterns and the results of function calls.
+type MatchFunc func(value interface{}, found bool, args []interface{}) (bool, error)
+
+// WithMatchers adds functions to evaluate operators in memory.
+// Matchers for the default operators are built in.
+func WithMatchers(matchers map[string]MatchFunc) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if parser.matchers == nil {
+			parser.matchers = make(map[string]MatchFunc)
+		}
+		for operator, f := range matchers {
+			if f == nil {
+				return fmt.Errorf("matcher of operator '%s' must not be nil", operator)
+			}
+			parser.matchers[operator] = f
+		}
+		return nil
+	}
+}
+
+// defaultMatchers contains the matchers of the default operators.
+var defaultMatchers = map[string]MatchFunc{
+	"==":           anyElement(matchEqual),
+	"!=":           not(anyElement(matchEqual)),
+	"=gt=":         anyElement(matchOrder(func(c int) bool { return c > 0 })),
+	"=ge=":         anyElement(matchOrder(func(c int) bool { return c >= 0 })),
+	"=lt=":         anyElement(matchOrder(func(c int) bool { return c < 0 })),
+	"=le=":         anyElement(matchOrder(func(c int) bool { return c <= 0 })),
+	"=in=":         anyElement(matchIn),
+	"=out=":        not(anyElement(matchIn)),
+	"=like=":       anyElement(matchEqual),
+	"=ilike=":      anyElement(matchEqual),
+	"=regex=":      anyElement(matchEqual),
+	"=null=":       matchNull,
+	"=exists=":     matchExists,
+	"=isempty=":    matchEmpty,
+	"=between=":    anyElement(matchBetween),
+	"=notbetween=": anyElement(matchNotBetween),
+	"=size=":       matchSize,
+	"=all=":        matchAll,
+	"=near=":       matchNear,
+	"=within=":     matchWithin,
+	"=intersects=": matchIntersects,
+}
+
+// Query is a compiled query, which can be evaluated against documents in memory.
+type Query struct {
+	root matcher
+}
+
+// Match reports whether the given document matches the query.
+// Documents are maps as decoded by encoding/json, nested fields
+// are addressed using dots, e.g. address.city.
+// Comparisons on arrays match if any element matches.
+func (q *Query) Match(doc interface{}) (bool, error) {
+	return q.root.match(doc)
+}
+
+// matcher is a compiled node of a query.
+type matcher interface {
+	match(doc interface{}) (bool, error)
+}
+
+// andMatcher matches if all of its children match.
+type andMatcher []matcher
+
+func (m andMatcher) match(doc interface{}) (bool, error) {
+	for _, child := range m {
+		ok, err := child.match(doc)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// orMatcher matches if any of its children matches.
+type orMatcher []matcher
+
+func (m orMatcher) match(doc interface{}) (bool, error) {
+	for _, child := range m {
+		ok, err := child.match(doc)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// comparisonMatcher matches a single comparison.
+type comparisonMatcher struct {
+	key      string
+	operator string
+	args     []interface{}
+	f        MatchFunc
+}
+
+func (m *comparisonMatcher) match(doc interface{}) (bool, error) {
+	value, found := lookup(doc, m.key)
+	ok, err := m.f(value, found, m.args)
+	if err != nil {
+		return false, fmt.Errorf("evaluating '%s%s' failed: %w", m.key, m.operator, err)
+	}
+	return ok, nil
+}
+
+// Compile validates the given string like Process and compiles it
+// into a query, which can be evaluated against documents in memory.
+// Keys are resolved like in Process, relative dates are resolved
+// when compiling.
+func (parser *Parser) Compile(s string, options ...func(*ProcessOptions) error) (*Query, error) {
+	return parser.CompileContext(context.Background(), s, options...)
+}
+
+// CompileContext works like Compile. The given context is passed
+// to the parser's FieldMapper when resolving keys.
+func (parser *Parser) CompileContext(ctx context.Context, s string, options ...func(*ProcessOptions) error) (*Query, error) {
+	opts, err := newProcessOptions(s, options...)
+	if err != nil {
+		return nil, err
+	}
+	parsing := parseState{
+		maxDepth: opts.maxDepth,
+	}
+	node := parse(s, 0, 0, &parsing)
+	state := processState{
+		keys:    make(map[string]bool),
+		errs:    parsing.errs,
+		collect: opts.collectErrors,
+		aborted: parsing.aborted,
+	}
+	if !state.stopped() {
+		node = parser.rewrite(node, &state)
+	}
+	var root matcher
+	if !state.stopped() {
+		root = parser.compile(ctx, node, &opts, &state)
+	}
+	if err := state.err(); err != nil {
+		return nil, err
+	}
+	return &Query{root: root}, nil
+}
+
+// compile validates the given node using the given options
+// and compiles it into a matcher. Errors are added to the state.
+func (parser *Parser) compile(ctx context.Context, node Node, opts *ProcessOptions, state *processState) matcher {
+	switch n := node.(type) {
+	case *OrNode:
+		ms := make(orMatcher, 0, len(n.Children))
+		for _, child := range n.Children {
+			ms = append(ms, parser.compile(ctx, child, opts, state))
+			if state.stopped() {
+				return nil
+			}
+		}
+		return ms
+	case *AndNode:
+		ms := make(andMatcher, 0, len(n.Children))
+		for _, child := range n.Children {
+			ms = append(ms, parser.compile(ctx, child, opts, state))
+			if state.stopped() {
+				return nil
+			}
+		}
+		return ms
+	case *ComparisonNode:
+		return parser.compileComparison(ctx, n, opts, state)
+	case nil:
+		// empty query, matches everything
+		return andMatcher(nil)
+	}
+	state.report(newError(node.Pos(), "unsupported node type %T", node))
+	return nil
+}
+
+// compileComparison validates the given comparison and compiles it into a matcher.
+func (parser *Parser) compileComparison(ctx context.Context, n *ComparisonNode, opts *ProcessOptions, state *processState) matcher {
+	c, ok := parser.resolveComparison(ctx, n, opts, state)
+	if !ok {
+		return nil
+	}
+	// compile sub-query
+	if c.operator == "=elem=" {
+		node, ok := parser.parseElem(n, c, opts, state)
+		if !ok {
+			return nil
+		}
+		state.enter(c)
+		sub := parser.compile(ctx, node, opts, state)
+		state.leave()
+		return &elemMatcher{key: c.key, sub: sub}
+	}
+	f, ok := parser.matchers[c.operator]
+	if !ok {
+		f, ok = defaultMatchers[c.operator]
+	}
+	if !ok {
+		state.report(newError(n.operatorPos(), "operator '%s' can not be evaluated in memory", c.operator))
+		return nil
+	}
+	if parser.dates != nil {
+		if err := parser.dates.checkRange(c.operator, c.values); err != nil {
+			state.report(invalidValueError(n, c.key, err))
+			return nil
+		}
+	}
+	args := make([]interface{}, 0, len(c.values))
+	for _, v := range c.values {
+		arg, err := parser.argument(c.operator, v)
+		if err != nil {
+			state.report(invalidValueError(n, c.key, err))
+			return nil
+		}
+		args = append(args, arg)
+	}
+	return &comparisonMatcher{
+		key:      c.key,
+		operator: c.operator,
+		args:     args,
+		f:        f,
+	}
+}
+
+// argument returns the typed value of the given value of a comparison.
+func (parser *Parser) argument(operator, value string) (interface{}, error) {
+	if parser.functions != nil && isFunctionCall(value) && !containsString(patternOperators, operator) {
+		return parser.evaluate(value)
+	}
+	if parser.dates != nil && !containsString(patternOperators, operator) {
+		t, _, ok, err := parser.dates.resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return t, nil
+		}
+	}
+	switch operator {
+	case "==", "!=":
+		if parts, ok := splitWildcards(value); ok {
+			return wildcardRegexp(parts, false), nil
+		}
+		value = unescapeWildcards(value)
+	case "=like=", "=ilike=":
+		parts, _ := splitWildcards(value)
+		return wildcardRegexp(parts, operator == "=ilike="), nil
+	case "=regex=":
+		return regexp.Compile(trimQuotes(value))
+	}
+	return literal(value), nil
+}
+
+// wildcardRegexp returns the given wildcard parts as anchored regular expression.
+func wildcardRegexp(parts []string, ignoreCase bool) *regexp.Regexp {
+	quoted := make([]string, len(parts))
+	for i, p := range parts {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	flags := "(?s)"
+	if ignoreCase {
+		flags = "(?is)"
+	}
+	return regexp.MustCompile(flags + "^" + strings.Join(quoted, ".*") + "$")
+}
+
+// lookup returns the value of the given key within the document.
+// Nested fields are separated by dots. Arrays can be indexed by numbers,
+// otherwise the values of all elements are collected.
+func lookup(doc interface{}, key string) (interface{}, bool) {
+	return lookupPath(doc, strings.Split(key, "."))
+}
+
+// lookupPath returns the value at the given path within the document.
+func lookupPath(doc interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return doc, true
+	}
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(child, path[1:])
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(v) {
+				return nil, false
+			}
+			return lookupPath(v[i], path[1:])
+		}
+		var res []interface{}
+		for _, e := range v {
+			r, ok := lookupPath(e, path)
+			if !ok {
+				continue
+			}
+			if arr, isArr := r.([]interface{}); isArr {
+				res = append(res, arr...)
+			} else {
+				res = append(res, r)
+			}
+		}
+		return res, len(res) > 0
+	}
+	return nil, false
+}
+
+// anyElement returns a matcher which matches if the given matcher
+// matches the value or, in case of an array, any of its elements.
+func anyElement(f MatchFunc) MatchFunc {
+	return func(value interface{}, found bool, args []interface{}) (bool, error) {
+		arr, ok := value.([]interface{})
+		if !ok {
+			return f(value, found, args)
+		}
+		for _, e := range arr {
+			ok, err := f(e, true, args)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// not returns a matcher which negates the given matcher.
+func not(f MatchFunc) MatchFunc {
+	return func(value interface{}, found bool, args []interface{}) (bool, error) {
+		ok, err := f(value, found, args)
+		return !ok && err == nil, err
+	}
+}
+
+// singleArg returns the only argument.
+func singleArg(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected a single value, got %d", len(args))
+	}
+	return args[0], nil
+}
+
+// boolArg returns the only argument as boolean.
+func boolArg(args []interface{}) (bool, error) {
+	arg, err := singleArg(args)
+	if err != nil {
+		return false, err
+	}
+	b, ok := arg.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected true or false, got %v", arg)
+	}
+	return b, nil
+}
+
+// matchEqual matches values equal to the argument.
+// Missing fields are equal to null.
+func matchEqual(value interface{}, found bool, args []interface{}) (bool, error) {
+	arg, err := singleArg(args)
+	if err != nil {
+		return false, err
+	}
+	return equal(value, arg), nil
+}
+
+// matchIn matches values equal to any of the arguments.
+func matchIn(value interface{}, found bool, args []interface{}) (bool, error) {
+	for _, arg := range args {
+		if equal(value, arg) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchOrder returns a matcher which compares the value with the argument
+// and checks the result using the given function.
+// Values which can not be compared do not match.
+func matchOrder(check func(c int) bool) MatchFunc {
+	return func(value interface{}, found bool, args []interface{}) (bool, error) {
+		arg, err := singleArg(args)
+		if err != nil {
+			return false, err
+		}
+		c, ok := compare(value, arg)
+		return ok && check(c), nil
+	}
+}
+
+// matchBetween matches values within the inclusive bounds given as arguments.
+func matchBetween(value interface{}, found bool, args []interface{}) (bool, error) {
+	within, _, err := between(value, args)
+	return within, err
+}
+
+// matchNotBetween matches values outside of the range given by the two arguments.
+// Like in mongodb and sql, missing fields, null values and values
+// which cannot be compared with the bounds do not match.
+func matchNotBetween(value interface{}, found bool, args []interface{}) (bool, error) {
+	within, comparable, err := between(value, args)
+	if err != nil || !found || value == nil {
+		return false, err
+	}
+	return comparable && !within, nil
+}
+
+// between checks if the given value is within the inclusive bounds given as arguments.
+// The second return value is false if the value cannot be compared with both bounds.
+func between(value interface{}, args []interface{}) (bool, bool, error) {
+	if len(args) != 2 {
+		return false, false, fmt.Errorf("expected two values, got %d", len(args))
+	}
+	low, lowOk := compare(value, args[0])
+	high, highOk := compare(value, args[1])
+	if !lowOk || !highOk {
+		return false, false, nil
+	}
+	return low >= 0 && high <= 0, true, nil
+}
+
+// matchNull matches missing fields and null values if the argument is true.
+func matchNull(value interface{}, found bool, args []interface{}) (bool, error) {
+	want, err := boolArg(args)
+	if err != nil {
+		return false, err
+	}
+	return (!found || value == nil) == want, nil
+}
+
+// matchExists matches existing fields if the argument is true.
+func matchExists(value interface{}, found bool, args []interface{}) (bool, error) {
+	want, err := boolArg(args)
+	if err != nil {
+		return false, err
+	}
+	return found == want, nil
+}
+
+// matchEmpty matches missing fields, null values, empty strings
+// and empty arrays if the argument is true.
+func matchEmpty(value interface{}, found bool, args []interface{}) (bool, error) {
+	want, err := boolArg(args)
+	if err != nil {
+		return false, err
+	}
+	empty := !found || value == nil
+	switch v := value.(type) {
+	case string:
+		empty = v == ""
+	case []interface{}:
+		empty = len(v) == 0
+	}
+	return empty == want, nil
+}
+
+// equal checks if the given value of a document equals the given argument.
+func equal(value, arg interface{}) bool {
+	switch a := arg.(type) {
+	case nil:
+		return value == nil
+	case *regexp.Regexp:
+		s, ok := value.(string)
+		return ok && a.MatchString(s)
+	case bool:
+		b, ok := value.(bool)
+		return ok && a == b
+	}
+	c, ok := compare(value, arg)
+	return ok && c == 0
+}
+
+// compare compares the given value of a document with the given argument.
+// Numbers, strings and dates can be compared, dates within documents
+// can be given as RFC 3339 strings. The second return value is false
+// if the values can not be compared.
+func compare(value, arg interface{}) (int, bool) {
+	if a, ok := toFloat(arg); ok {
+		v, ok := toFloat(value)
+		if !ok {
+			return 0, false
+		}
+		return compareFloats(v, a), true
+	}
+	switch a := arg.(type) {
+	case string:
+		v, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v, a), true
+	case time.Time:
+		v, ok := toTime(value)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case v.Before(a):
+			return -1, true
+		case v.After(a):
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// compareFloats compares the given numbers.
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// toFloat converts the given number to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toTime converts the given time or RFC 3339 string to time.Time.
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		res, err := time.Parse(time.RFC3339Nano, t)
+		return res, err == nil
+	}
+	return time.Time{}, false
+}
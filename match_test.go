@@ -0,0 +1,144 @@
+package rsql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParser_Compile(t *testing.T) {
+	var doc map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"name": "John*",
+		"status": "A",
+		"qty": 25,
+		"price": 9.5,
+		"active": true,
+		"deleted": null,
+		"tags": [ "a", "b" ],
+		"empty": "",
+		"createdAt": "2021-08-01T10:00:00Z",
+		"address": { "city": "Bern", "zip": "3000" },
+		"items": [ { "sku": "X", "qty": 2 }, { "sku": "Y", "qty": 8 } ]
+	}`), &doc)
+	if err != nil {
+		t.Fatalf("error while unmarshalling document: %s", err)
+	}
+	tests := []struct {
+		s       string
+		want    bool
+		wantErr bool
+	}{
+		{s: ``, want: true},
+		{s: `status==A`, want: true},
+		{s: `status=="A"`, want: true},
+		{s: `status==B`, want: false},
+		{s: `status!=B`, want: true},
+		{s: `missing!=B`, want: true},
+		{s: `missing==null`, want: true},
+		{s: `name==Jo*`, want: true},
+		{s: `name==jo*`, want: false},
+		{s: `name==John\*`, want: true},
+		{s: `name!=Jo*`, want: false},
+		{s: `qty==25`, want: true},
+		{s: `qty=gt=20;qty=lt=30`, want: true},
+		{s: `qty=ge=26,price=le=9.5`, want: true},
+		{s: `qty=gt=abc`, want: false},
+		{s: `active==true`, want: true},
+		{s: `active==false`, want: false},
+		{s: `status=in=(A,B)`, want: true},
+		{s: `status=out=(A,B)`, want: false},
+		{s: `tags==b`, want: true},
+		{s: `tags!=b`, want: false},
+		{s: `tags=in=(c,d)`, want: false},
+		{s: `address.city==Bern`, want: true},
+		{s: `items.sku==Y`, want: true},
+		{s: `items.1.qty==8`, want: true},
+		{s: `items.qty=gt=5`, want: true},
+		{s: `name=like=*ohn\*`, want: true},
+		{s: `name=ilike=JOHN*`, want: true},
+		{s: `status=regex="^[A-C]$"`, want: true},
+		{s: `deleted=null=true`, want: true},
+		{s: `missing=null=true`, want: true},
+		{s: `status=null=true`, want: false},
+		{s: `deleted=exists=true`, want: true},
+		{s: `missing=exists=false`, want: true},
+		{s: `empty=isempty=true`, want: true},
+		{s: `missing=isempty=true`, want: true},
+		{s: `status=isempty=false`, want: true},
+		{s: `qty=between=(20,25)`, want: true},
+		{s: `qty=notbetween=(20,25)`, want: false},
+		{s: `qty=notbetween=(30,40)`, want: true},
+		{s: `missing=notbetween=(10,20)`, want: false},
+		{s: `deleted=notbetween=(10,20)`, want: false},
+		{s: `name=notbetween=(2,6)`, want: false},
+		{s: `name=notbetween=(A,B)`, want: true},
+		{s: `createdAt=gt=2021-07-31`, want: true},
+		{s: `createdAt=lt=2021-08-01T09:00:00Z`, want: false},
+		{s: `createdAt=regex=2021-08-01`, want: true},
+		{s: `createdAt=like=2021-08-01*`, want: true},
+		{s: `createdAt=ilike=2021-08-01t10:00:00z`, want: true},
+		{s: `status==A;(qty=lt=10,address.zip=="3000")`, want: true},
+		{s: `status==B,(qty=lt=10;address.zip=="3000")`, want: false},
+		{s: `status=custom=A`, wantErr: true},
+		{s: `status==(A,B)`, wantErr: true},
+	}
+	parser, err := NewParser(Mongo(), WithDates(DateOptions{}))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			q, err := parser.Compile(tt.s)
+			if err == nil {
+				var got bool
+				got, err = q.Match(doc)
+				if got != tt.want {
+					t.Errorf("Match() got = %v, want %v", got, tt.want)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() or Match() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParser_CompileOptions(t *testing.T) {
+	parser, err := NewParser(Mongo(),
+		WithKeyTransformers(func(s string) string {
+			if s == "id" {
+				return "_id"
+			}
+			return s
+		}),
+		WithOperators(Operator{"=custom=", func(key, value string) string { return "" }}),
+		WithMatchers(map[string]MatchFunc{
+			"=custom=": func(value interface{}, found bool, args []interface{}) (bool, error) {
+				return found && value == args[0], nil
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	doc := map[string]interface{}{"_id": "x", "n": int64(3), "at": time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)}
+	q, err := parser.Compile(`id=custom=x;n==3`, SetAllowedKeys([]string{"_id", "n"}))
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if ok, err := q.Match(doc); !ok || err != nil {
+		t.Errorf("Match() got = %v, %v, want true", ok, err)
+	}
+	if _, err := parser.Compile(`at==1`, SetAllowedKeys([]string{"_id", "n"})); err == nil {
+		t.Errorf("Compile() expected error for key which is not allowed")
+	}
+	if _, err := parser.Compile(`a==1;b=x=1;c=y=1`, SetCollectErrors(true)); err == nil {
+		t.Errorf("Compile() expected errors")
+	} else if errs, ok := err.(ErrorList); !ok || len(errs) != 2 {
+		t.Errorf("Compile() error = %v, want 2 errors", err)
+	}
+	if _, err := NewParser(Mongo(), WithMatchers(map[string]MatchFunc{"=x=": nil})); err == nil {
+		t.Errorf("WithMatchers() expected error for nil matcher")
+	}
+}
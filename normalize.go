@@ -0,0 +1,139 @@
+package rsql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Normalize returns the given syntax tree in normalized form, so equivalent
+// queries like (a==1;(b==2));a==1 and b==2;a==1 result in the same tree.
+// Nested AND and OR nodes of the same kind are flattened, duplicate children
+// are removed and the children are sorted by their canonical form (see Format).
+// Groups with a single child are replaced by the child, empty groups are removed.
+// Whitespace around keys, values and list items is removed. Sub-queries of =elem=
+// comparisons are normalized as well, other values are kept as they are.
+// The given tree is not modified, normalizing a nil Node results in nil.
+func Normalize(node Node) Node {
+	switch n := node.(type) {
+	case *AndNode:
+		children := normalizeChildren(n.Children, func(child Node) []Node {
+			if and, ok := child.(*AndNode); ok {
+				return and.Children
+			}
+			return nil
+		})
+		if len(children) < 2 {
+			return firstNode(children)
+		}
+		return &AndNode{Position: n.Position, Children: children}
+	case *OrNode:
+		children := normalizeChildren(n.Children, func(child Node) []Node {
+			if or, ok := child.(*OrNode); ok {
+				return or.Children
+			}
+			return nil
+		})
+		if len(children) < 2 {
+			return firstNode(children)
+		}
+		return &OrNode{Position: n.Position, Children: children}
+	case *ComparisonNode:
+		value := canonicalValue(n.Value)
+		if n.Operator == "=elem=" && strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+			if inner, err := Parse(value[1 : len(value)-1]); err == nil && inner != nil {
+				value = "(" + Format(Normalize(inner)) + ")"
+			}
+		}
+		return &ComparisonNode{
+			Position: n.Position,
+			Key:      trimSpace(n.Key),
+			Operator: n.Operator,
+			Value:    value,
+		}
+	}
+	return node
+}
+
+// normalizeChildren normalizes the given children. Children for which nested returns
+// nodes (the children of a group of the same kind) are replaced by these nodes.
+// Duplicates are removed and the result is sorted by the canonical form of the children.
+func normalizeChildren(children []Node, nested func(Node) []Node) []Node {
+	type entry struct {
+		node Node
+		s    string
+	}
+	var entries []entry
+	seen := make(map[string]bool)
+	var add func(children []Node)
+	add = func(children []Node) {
+		for _, child := range children {
+			child = Normalize(child)
+			if child == nil {
+				continue
+			}
+			if grandchildren := nested(child); grandchildren != nil {
+				add(grandchildren)
+				continue
+			}
+			s := Format(child)
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			entries = append(entries, entry{node: child, s: s})
+		}
+	}
+	add(children)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].s < entries[j].s
+	})
+	res := make([]Node, len(entries))
+	for i, e := range entries {
+		res[i] = e.node
+	}
+	return res
+}
+
+// firstNode returns the first of the given nodes or nil if there are none.
+func firstNode(nodes []Node) Node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// Hash returns a stable hash of the normalized form of the given syntax tree,
+// a hex encoded sha256 sum. Equivalent queries as described in Normalize
+// have the same hash, e.g. to deduplicate saved filters or as cache key.
+func Hash(node Node) string {
+	sum := sha256.Sum256([]byte(Format(Normalize(node))))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalValue returns the given comparison value in canonical form.
+func canonicalValue(value string) string {
+	value = trimSpace(value)
+	values, isList := splitValues(value)
+	if !isList {
+		return value
+	}
+	// sub-query, e.g. for =elem=
+	if node, err := Parse(value[1 : len(value)-1]); err == nil && node != nil {
+		return "(" + Format(node) + ")"
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return joinValues(values, true)
+}
+
+// trimSpace removes leading and trailing whitespace from s,
+// unless s consists of whitespace only.
+func trimSpace(s string) string {
+	if trimmed := strings.TrimSpace(s); trimmed != "" {
+		return trimmed
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package rsql
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: ``, want: ``},
+		{s: `a==1`, want: `a==1`},
+		{s: ` b == 1 ;a==1`, want: `a==1;b==1`},
+		{s: `(a==1;(b==2));a==1`, want: `a==1;b==2`},
+		{s: `b==2;a==1`, want: `a==1;b==2`},
+		{s: `c==3,(b==2,(a==1;a==1))`, want: `a==1,b==2,c==3`},
+		{s: `(c==3,b==2);a==1;(b==2,c==3)`, want: `a==1;(b==2,c==3)`},
+		{s: `(b==2;a==1),c==3`, want: `a==1;b==2,c==3`},
+		{s: `a=in=(2, 1);a=between=(5,1)`, want: `a=between=(5,1);a=in=(2,1)`},
+		{s: `items=elem=(sku==X;(qty=gt=5;sku==X))`, want: `items=elem=(qty=gt=5;sku==X)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			node, err := Parse(tt.s)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := Format(Normalize(node))
+			if got != tt.want {
+				t.Errorf("Normalize() = %v, want %v", got, tt.want)
+			}
+			want, err := Parse(tt.want)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if Hash(node) != Hash(want) {
+				t.Errorf("Hash() differs for %v and %v", tt.s, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize_Unmodified(t *testing.T) {
+	node, err := Parse(`b==2;(c==3,a==1)`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	Normalize(node)
+	if got := Format(node); got != `b==2;(c==3,a==1)` {
+		t.Errorf("Normalize() modified the given node: %v", got)
+	}
+}
+
+func TestNormalize_Stable(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		s := randomQuery(r, 4)
+		node, err := Parse(s)
+		if err != nil {
+			t.Fatalf("randomQuery() returned invalid query %q: %v", s, err)
+		}
+		normalized := Format(Normalize(node))
+		parsed, err := Parse(normalized)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v, normalized from %q", normalized, err, s)
+		}
+		if again := Format(Normalize(parsed)); again != normalized {
+			t.Fatalf("Normalize() not stable for %q: %q != %q", s, again, normalized)
+		}
+		if Hash(node) != Hash(parsed) {
+			t.Fatalf("Hash() differs for %q and %q", s, normalized)
+		}
+	}
+	if Hash(nil) == Hash(&ComparisonNode{Key: "a", Operator: "==", Value: "1"}) {
+		t.Errorf("Hash() expected to differ")
+	}
+}
@@ -0,0 +1,354 @@
+package rsql
+
+import (
+	"math/big"
+	"strings"
+)
+
+// bound is a numeric range bound like =gt=5.
+type bound struct {
+	value     *big.Rat
+	lower     bool
+	inclusive bool
+}
+
+// boundOperators contains the operators of range bounds.
+var boundOperators = map[string]bound{
+	"=gt=": {lower: true},
+	"=ge=": {lower: true, inclusive: true},
+	"=lt=": {},
+	"=le=": {inclusive: true},
+}
+
+// Optimize returns an equivalent, smaller syntax tree for the given one,
+// assuming the operators have their default meaning:
+//   - equalities on the same key within ORs are merged to =in=,
+//     e.g. s==a,s==b,s=in=(c) results in s=in=(a,b,c)
+//   - equality and =in= comparisons within ANDs implied by another one on the same key are removed,
+//     e.g. s==a;s=in=(a,b) results in s==a, inequalities on the same key within ANDs are merged to =out=
+//   - numeric range bounds on the same key are merged, e.g. p=gt=5;p=gt=10 results in p=gt=10
+//     and p=gt=5,p=gt=10 in p=gt=5
+//   - redundant comparisons are removed, e.g. p==7;p=gt=5 results in p==7
+//   - duplicate list values are removed and lists with a single value are folded,
+//     e.g. s=in=(a) results in s==a
+//
+// Values containing wildcards are kept as they are. Contradicting comparisons
+// (e.g. s==a;s==b) are not removed, as they could match arrays. The result is normalized, see Normalize.
+// The given tree is not modified, optimizing a nil Node results in nil.
+func Optimize(node Node) Node {
+	return Normalize(optimize(Normalize(node)))
+}
+
+// optimize optimizes the given normalized syntax tree.
+func optimize(node Node) Node {
+	switch n := node.(type) {
+	case *AndNode:
+		children := optimizeChildren(n.Children)
+		children = mergeLists(children, "==", "=in=", true)
+		children = mergeLists(children, "!=", "=out=", false)
+		children = mergeBounds(children, true)
+		children = dropRedundant(children, true)
+		if len(children) < 2 {
+			return firstNode(children)
+		}
+		return &AndNode{Position: n.Position, Children: children}
+	case *OrNode:
+		children := optimizeChildren(n.Children)
+		children = mergeLists(children, "==", "=in=", false)
+		children = mergeBounds(children, false)
+		children = dropRedundant(children, false)
+		if len(children) < 2 {
+			return firstNode(children)
+		}
+		return &OrNode{Position: n.Position, Children: children}
+	case *ComparisonNode:
+		return optimizeComparison(n)
+	}
+	return node
+}
+
+// optimizeChildren optimizes the given children, removing empty ones.
+func optimizeChildren(children []Node) []Node {
+	res := make([]Node, 0, len(children))
+	for _, child := range children {
+		if child = optimize(child); child != nil {
+			res = append(res, child)
+		}
+	}
+	return res
+}
+
+// optimizeComparison optimizes the sub-query of =elem= comparisons,
+// removes duplicate values of =in= and =out= lists and folds lists with a single value.
+func optimizeComparison(n *ComparisonNode) Node {
+	switch n.Operator {
+	case "=elem=":
+		if len(n.Value) < 2 || n.Value[0] != '(' || n.Value[len(n.Value)-1] != ')' {
+			return n
+		}
+		inner, err := Parse(n.Value[1 : len(n.Value)-1])
+		if err != nil || inner == nil {
+			return n
+		}
+		res := *n
+		res.Value = "(" + Format(Optimize(inner)) + ")"
+		return &res
+	case "=in=":
+		if values, ok := listValues(n, "==", "=in="); ok {
+			return listComparison(n, "==", "=in=", values)
+		}
+	case "=out=":
+		if values, ok := listValues(n, "!=", "=out="); ok {
+			return listComparison(n, "!=", "=out=", values)
+		}
+	}
+	return n
+}
+
+// listValues returns the deduplicated values of the given node if it is a comparison
+// using the single operator (e.g. ==) with a single value or the list operator (e.g. =in=) with a list.
+// Values containing wildcards are not returned.
+func listValues(node Node, single, list string) ([]string, bool) {
+	n, ok := node.(*ComparisonNode)
+	if !ok {
+		return nil, false
+	}
+	values, isList := splitValues(n.Value)
+	if n.Operator == single && isList || n.Operator == list && !isList || n.Operator != single && n.Operator != list {
+		return nil, false
+	}
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		v = trimSpace(v)
+		if strings.Contains(v, "*") {
+			return nil, false
+		}
+		res = unionValues(res, []string{v})
+	}
+	return res, true
+}
+
+// listComparison returns a comparison for the given key and values, using
+// the single operator if there is one value and the list operator otherwise.
+func listComparison(n *ComparisonNode, single, list string, values []string) *ComparisonNode {
+	if len(values) == 1 {
+		return &ComparisonNode{Position: n.Position, Key: n.Key, Operator: single, Value: values[0]}
+	}
+	return &ComparisonNode{Position: n.Position, Key: n.Key, Operator: list, Value: joinValues(values, true)}
+}
+
+// mergeLists merges the comparisons on the same key using the single operator (e.g. ==)
+// or the list operator (e.g. =in=), see listValues. If subset is true, the comparisons are
+// only merged if the values of one comparison are a subset of all the others, which are kept.
+// This is the case for =in= comparisons within ANDs, as intersecting the values would not work
+// for arrays. Otherwise, the values are joined. The merged comparison replaces the first one.
+func mergeLists(children []Node, single, list string, subset bool) []Node {
+	type merged struct {
+		first  int
+		count  int
+		values []string
+		// conflict is set if the comparisons cannot be merged
+		conflict bool
+	}
+	byKey := make(map[string]*merged)
+	for i, child := range children {
+		values, ok := listValues(child, single, list)
+		if !ok {
+			continue
+		}
+		key := child.(*ComparisonNode).Key
+		m, ok := byKey[key]
+		if !ok {
+			byKey[key] = &merged{first: i, count: 1, values: values}
+			continue
+		}
+		m.count++
+		switch {
+		case !subset:
+			m.values = unionValues(m.values, values)
+		case isSubset(values, m.values):
+			m.values = values
+		case !isSubset(m.values, values):
+			m.conflict = true
+		}
+	}
+	res := make([]Node, 0, len(children))
+	for i, child := range children {
+		if _, ok := listValues(child, single, list); !ok {
+			res = append(res, child)
+			continue
+		}
+		n := child.(*ComparisonNode)
+		m := byKey[n.Key]
+		if m.count < 2 || m.conflict {
+			res = append(res, child)
+			continue
+		}
+		if m.first == i {
+			res = append(res, listComparison(n, single, list, m.values))
+		}
+	}
+	return res
+}
+
+// unionValues returns the values of a followed by the values of b which are not in a.
+func unionValues(a, b []string) []string {
+	for _, v := range b {
+		if !containsString(a, v) {
+			a = append(a, v)
+		}
+	}
+	return a
+}
+
+// isSubset checks if all values of a are in b.
+func isSubset(a, b []string) bool {
+	for _, v := range a {
+		if !containsString(b, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// numericValue returns the value of the given number literal.
+func numericValue(s string) (*big.Rat, bool) {
+	switch literal(s).(type) {
+	case int64, float64:
+		return new(big.Rat).SetString(s)
+	}
+	return nil, false
+}
+
+// boundOf returns the key and the bound of the given node
+// if it is a comparison using a range operator with a numeric value.
+func boundOf(node Node) (string, bound, bool) {
+	n, ok := node.(*ComparisonNode)
+	if !ok {
+		return "", bound{}, false
+	}
+	b, ok := boundOperators[n.Operator]
+	if !ok {
+		return "", bound{}, false
+	}
+	if b.value, ok = numericValue(trimSpace(n.Value)); !ok {
+		return "", bound{}, false
+	}
+	return n.Key, b, true
+}
+
+// tighter checks if bound a is tighter than bound b, both being lower or upper bounds.
+func (a bound) tighter(b bound) bool {
+	c := a.value.Cmp(b.value)
+	if !a.lower {
+		c = -c
+	}
+	return c > 0 || c == 0 && !a.inclusive && b.inclusive
+}
+
+// satisfies checks if the given value is within the bound.
+func (a bound) satisfies(v *big.Rat) bool {
+	c := v.Cmp(a.value)
+	if !a.lower {
+		c = -c
+	}
+	return c > 0 || c == 0 && a.inclusive
+}
+
+// mergeBounds keeps the tightest lower and upper bound per key if and is true,
+// the loosest ones otherwise. Other bounds are removed.
+func mergeBounds(children []Node, and bool) []Node {
+	type best struct {
+		index int
+		bound bound
+	}
+	bests := make(map[string]*best)
+	id := func(key string, b bound) string {
+		if b.lower {
+			return ">" + key
+		}
+		return "<" + key
+	}
+	for i, child := range children {
+		key, b, ok := boundOf(child)
+		if !ok {
+			continue
+		}
+		cur, ok := bests[id(key, b)]
+		if !ok || and && b.tighter(cur.bound) || !and && cur.bound.tighter(b) {
+			bests[id(key, b)] = &best{index: i, bound: b}
+		}
+	}
+	res := make([]Node, 0, len(children))
+	for i, child := range children {
+		if key, b, ok := boundOf(child); ok && bests[id(key, b)].index != i {
+			continue
+		}
+		res = append(res, child)
+	}
+	return res
+}
+
+// dropRedundant removes comparisons implied by other comparisons on the same key.
+// If and is true, bounds satisfied by all values of the only equality or =in=
+// comparison are removed, e.g. p=gt=5 from p==7;p=gt=5. Otherwise,
+// equality and =in= comparisons whose values all satisfy a bound are removed,
+// e.g. p==7 from p==7,p=gt=5.
+func dropRedundant(children []Node, and bool) []Node {
+	// numeric values of equality and =in= comparisons
+	numbers := make(map[int][]*big.Rat)
+	counts := make(map[string]int)
+	for i, child := range children {
+		values, ok := listValues(child, "==", "=in=")
+		if !ok {
+			continue
+		}
+		counts[child.(*ComparisonNode).Key]++
+		var nums []*big.Rat
+		for _, v := range values {
+			num, ok := numericValue(v)
+			if !ok {
+				nums = nil
+				break
+			}
+			nums = append(nums, num)
+		}
+		if nums != nil {
+			numbers[i] = nums
+		}
+	}
+	satisfied := func(nums []*big.Rat, b bound) bool {
+		for _, num := range nums {
+			if !b.satisfies(num) {
+				return false
+			}
+		}
+		return true
+	}
+	drop := make(map[int]bool)
+	for i, child := range children {
+		key, b, ok := boundOf(child)
+		if !ok {
+			continue
+		}
+		for j, nums := range numbers {
+			other := children[j].(*ComparisonNode)
+			if other.Key != key || !satisfied(nums, b) {
+				continue
+			}
+			if and && counts[key] == 1 {
+				drop[i] = true
+			}
+			if !and {
+				drop[j] = true
+			}
+		}
+	}
+	res := make([]Node, 0, len(children))
+	for i, child := range children {
+		if !drop[i] {
+			res = append(res, child)
+		}
+	}
+	return res
+}
@@ -0,0 +1,116 @@
+package rsql
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: ``, want: ``},
+		{s: `s==a`, want: `s==a`},
+		{s: `s==a,s==b,s==c`, want: `s=in=(a,b,c)`},
+		{s: `s==a,s=in=(b,a),t==x,s==c`, want: `s=in=(a,c,b),t==x`},
+		{s: `s==a,s==Jo*`, want: `s==Jo*,s==a`},
+		{s: `s=in=(a)`, want: `s==a`},
+		{s: `s=in=(a, a ,b)`, want: `s=in=(a,b)`},
+		{s: `s=in=(Jo*)`, want: `s=in=(Jo*)`},
+		{s: `s=out=(a)`, want: `s!=a`},
+		{s: `s!=a;s!=b;s=out=(c)`, want: `s=out=(a,b,c)`},
+		{s: `s==a;s=in=(a,b)`, want: `s==a`},
+		{s: `s=in=(a,b,c);s=in=(b,a)`, want: `s=in=(b,a)`},
+		{s: `s=in=(a,b);s=in=(b,c)`, want: `s=in=(a,b);s=in=(b,c)`},
+		{s: `s==a;s==b`, want: `s==a;s==b`},
+		{s: `p=gt=5;p=gt=10`, want: `p=gt=10`},
+		{s: `p=gt=5,p=gt=10`, want: `p=gt=5`},
+		{s: `p=gt=5;p=ge=5`, want: `p=gt=5`},
+		{s: `p=gt=5,p=ge=5`, want: `p=ge=5`},
+		{s: `p=lt=5;p=le=2.5;p=lt=1e1`, want: `p=le=2.5`},
+		{s: `p=lt=5,p=le=2.5,p=lt=1e1`, want: `p=lt=1e1`},
+		{s: `p=gt=5;p=lt=10;p=gt=7`, want: `p=gt=7;p=lt=10`},
+		{s: `p=gt=10;p=lt=5`, want: `p=gt=10;p=lt=5`},
+		{s: `p=gt="5";p=gt=10`, want: `p=gt="5";p=gt=10`},
+		{s: `p=gt=9007199254740993;p=gt=9007199254740992`, want: `p=gt=9007199254740993`},
+		{s: `p==7;p=gt=5`, want: `p==7`},
+		{s: `p=in=(6,7);p=gt=5;p=lt=7`, want: `p=in=(6,7);p=lt=7`},
+		{s: `p==7,p=gt=5`, want: `p=gt=5`},
+		{s: `p==5,p=gt=5`, want: `p==5,p=gt=5`},
+		{s: `p==7,p==9,p=gt=5`, want: `p=gt=5`},
+		{s: `(s==a,s==b);(p=gt=5;p=gt=10)`, want: `p=gt=10;s=in=(a,b)`},
+		{s: `items=elem=(s==a,s==b)`, want: `items=elem=(s=in=(a,b))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			node, err := Parse(tt.s)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := Format(Optimize(node)); got != tt.want {
+				t.Errorf("Optimize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptimize_Match(t *testing.T) {
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	var docs []map[string]interface{}
+	for _, s := range []interface{}{"a", "b", "Jo", int64(5), int64(7), 2.5, nil, []interface{}{int64(1), int64(3)}, []interface{}{"a", "c"}} {
+		for _, p := range []interface{}{int64(5), int64(7), 10.5, "5", []interface{}{int64(2), int64(11)}} {
+			docs = append(docs, map[string]interface{}{"s": s, "p": p})
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	comparisons := func() string {
+		keys := []string{"s", "p"}
+		operators := []string{"==", "!=", "=gt=", "=ge=", "=lt=", "=le="}
+		values := []string{"a", "b", "Jo*", "5", "7", "2.5", "10"}
+		lists := []string{"(a,b)", "(a)", "(5,7)", "(1,3,b)", "(7,7)"}
+		if r.Intn(3) == 0 {
+			return keys[r.Intn(len(keys))] + []string{"=in=", "=out="}[r.Intn(2)] + lists[r.Intn(len(lists))]
+		}
+		return keys[r.Intn(len(keys))] + operators[r.Intn(len(operators))] + values[r.Intn(len(values))]
+	}
+	for i := 0; i < 2000; i++ {
+		s := comparisons()
+		for j := r.Intn(4); j >= 0; j-- {
+			sep := ";"
+			if r.Intn(2) == 0 {
+				sep = ","
+			}
+			s = "(" + s + ")" + sep + comparisons()
+		}
+		node, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", s, err)
+		}
+		optimized := Format(Optimize(node))
+		q, err := parser.Compile(s)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", s, err)
+		}
+		o, err := parser.Compile(optimized)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v, optimized from %q", optimized, err, s)
+		}
+		for _, doc := range docs {
+			want, err := q.Match(doc)
+			if err != nil {
+				t.Fatalf("Match() of %q error = %v", s, err)
+			}
+			got, err := o.Match(doc)
+			if err != nil {
+				t.Fatalf("Match() of %q error = %v", optimized, err)
+			}
+			if got != want {
+				t.Fatalf("Match() of %q = %v, want %v as for %q, document %v", optimized, got, want, s, doc)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package rsql
+
+import (
+	"errors"
+	"strings"
+)
+
+// parseState keeps track of the state while parsing a string.
+type parseState struct {
+	maxDepth int
+	errs     ErrorList
+	// aborted is set if parsing needs to be stopped, e.g. because a limit was exceeded.
+	aborted bool
+}
+
+// report adds the given error, shifting its position by offset.
+func (state *parseState) report(offset int, err error) {
+	var e *Error
+	if errors.As(err, &e) {
+		state.errs = append(state.errs, &Error{Pos: offset + e.Pos, Msg: e.Msg, Err: e.Err})
+		return
+	}
+	state.errs = append(state.errs, &Error{Pos: offset, Msg: err.Error(), Err: err})
+}
+
+// Parse parses the given rsql string and returns its syntax tree.
+// Only the syntax is checked, keys and operators are not validated.
+// Nested groups of the same kind are flattened, e.g. (a==1;b==2);c==3
+// results in an AND node with three comparisons.
+// If the string contains syntax errors, an ErrorList is returned.
+// Parsing an empty string results in a nil Node.
+func Parse(s string) (Node, error) {
+	state := parseState{}
+	node := parse(s, 0, 0, &state)
+	if len(state.errs) > 0 {
+		state.errs.sortByPos()
+		return nil, state.errs
+	}
+	return node, nil
+}
+
+// parse parses the given string, offset being the position of
+// the string within the whole query and depth the level of nesting.
+// Errors are added to the state, invalid parts are skipped.
+func parse(s string, offset, depth int, state *parseState) Node {
+	if state.maxDepth > 0 && depth > state.maxDepth {
+		state.report(offset, newError(0, "%w", &LimitError{Limit: LimitDepth, Max: state.maxDepth}))
+		state.aborted = true
+		return nil
+	}
+	// get ORs
+	locations, err := findORs(s, -1)
+	if err != nil {
+		state.report(offset, err)
+		return nil
+	}
+	var ors []Node
+	for _, loc := range locations {
+		start, end := loc[0], loc[1]
+		content := s[start:end]
+		contentOffset := offset + start
+		// handle ANDs
+		locs, err := findANDs(content, -1)
+		if err != nil {
+			state.report(contentOffset, err)
+			continue
+		}
+		var ands []Node
+		for _, l := range locs {
+			start, end := l[0], l[1]
+			content := content[start:end]
+			pos := contentOffset + start
+			// handle parentheses
+			parentheses, err := findOuterParentheses(content, -1)
+			if err != nil {
+				state.report(pos, err)
+				continue
+			}
+			for _, p := range parentheses {
+				start, end := p[0], p[1]
+				if end == start+1 {
+					state.report(pos+start, errors.New("empty parentheses"))
+					continue
+				}
+				// handle nested
+				node := parse(content[start+1:end], pos+start+1, depth+1, state)
+				if state.aborted {
+					return nil
+				}
+				if node != nil {
+					ands = append(ands, node)
+				}
+			}
+			if len(parentheses) > 0 {
+				// location is already fully handled
+				continue
+			}
+			// if no parentheses, it should be an operation
+			node, err := parseComparison(content)
+			if err != nil {
+				state.report(pos, err)
+				continue
+			}
+			node.Position = pos
+			ands = append(ands, node)
+		}
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else if len(ands) > 1 {
+			ors = append(ors, &AndNode{Position: contentOffset, Children: flatten(ands, true)})
+		}
+	}
+	if len(ors) == 1 {
+		return ors[0]
+	}
+	if len(ors) > 1 {
+		return &OrNode{Position: offset, Children: flatten(ors, false)}
+	}
+	return nil
+}
+
+// flatten replaces nested groups of the same kind by their children,
+// e.g. (b==2;c==3) within a==1;(b==2;c==3). If and is true,
+// the given nodes are the children of an AND node, of an OR node otherwise.
+func flatten(nodes []Node, and bool) []Node {
+	res := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *AndNode:
+			if and {
+				res = append(res, n.Children...)
+				continue
+			}
+		case *OrNode:
+			if !and {
+				res = append(res, n.Children...)
+				continue
+			}
+		}
+		res = append(res, node)
+	}
+	return res
+}
+
+// parseComparison parses a single comparison like a==1.
+func parseComparison(s string) (*ComparisonNode, error) {
+	keyLoc := reKey.FindStringIndex(s)
+	if keyLoc == nil {
+		return nil, newError(0, "missing key in operation '%s'", s)
+	}
+	opLoc := reOperator.FindStringIndex(s)
+	if opLoc == nil || opLoc[0] != keyLoc[1] {
+		return nil, newError(keyLoc[1], "missing or invalid operator in operation '%s'", s)
+	}
+	valueLoc := reValue.FindStringIndex(s)
+	if valueLoc != nil && valueLoc[0] != opLoc[1] && strings.HasPrefix(s[opLoc[1]:], "(") && strings.HasSuffix(s, ")") {
+		// values in parentheses may contain sub-queries, e.g. items=elem=(qty=gt=5;sku==X)
+		valueLoc = []int{opLoc[1], len(s)}
+	}
+	if valueLoc == nil || valueLoc[0] != opLoc[1] {
+		return nil, newError(opLoc[1], "missing or invalid value in operation '%s'", s)
+	}
+	return &ComparisonNode{
+		Key:      s[keyLoc[0]:keyLoc[1]],
+		Operator: s[opLoc[0]:opLoc[1]],
+		Value:    s[valueLoc[0]:valueLoc[1]],
+	}, nil
+}
@@ -0,0 +1,154 @@
+package rsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Node
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			s:    "",
+			want: nil,
+		},
+		{
+			name: "comparison",
+			s:    "a==1",
+			want: &ComparisonNode{Position: 0, Key: "a", Operator: "==", Value: "1"},
+		},
+		{
+			name: "list",
+			s:    "a=in=(1,2,3)",
+			want: &ComparisonNode{Position: 0, Key: "a", Operator: "=in=", Value: "(1,2,3)"},
+		},
+		{
+			name: "single parentheses",
+			s:    "(a==1)",
+			want: &ComparisonNode{Position: 1, Key: "a", Operator: "==", Value: "1"},
+		},
+		{
+			name: "and",
+			s:    "a==1;b!=2",
+			want: &AndNode{
+				Position: 0,
+				Children: []Node{
+					&ComparisonNode{Position: 0, Key: "a", Operator: "==", Value: "1"},
+					&ComparisonNode{Position: 5, Key: "b", Operator: "!=", Value: "2"},
+				},
+			},
+		},
+		{
+			name: "or with nested and",
+			s:    "c=gt=5,(a==1;b==2)",
+			want: &OrNode{
+				Position: 0,
+				Children: []Node{
+					&ComparisonNode{Position: 0, Key: "c", Operator: "=gt=", Value: "5"},
+					&AndNode{
+						Position: 8,
+						Children: []Node{
+							&ComparisonNode{Position: 8, Key: "a", Operator: "==", Value: "1"},
+							&ComparisonNode{Position: 13, Key: "b", Operator: "==", Value: "2"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "nested and is flattened",
+			s:    "(a==1;b==2);c==3",
+			want: &AndNode{
+				Position: 0,
+				Children: []Node{
+					&ComparisonNode{Position: 1, Key: "a", Operator: "==", Value: "1"},
+					&ComparisonNode{Position: 6, Key: "b", Operator: "==", Value: "2"},
+					&ComparisonNode{Position: 12, Key: "c", Operator: "==", Value: "3"},
+				},
+			},
+		},
+		{
+			name: "or within and is kept",
+			s:    "a==1,(b==2,c==3);d==4",
+			want: &OrNode{
+				Position: 0,
+				Children: []Node{
+					&ComparisonNode{Position: 0, Key: "a", Operator: "==", Value: "1"},
+					&AndNode{
+						Position: 5,
+						Children: []Node{
+							&OrNode{
+								Position: 6,
+								Children: []Node{
+									&ComparisonNode{Position: 6, Key: "b", Operator: "==", Value: "2"},
+									&ComparisonNode{Position: 11, Key: "c", Operator: "==", Value: "3"},
+								},
+							},
+							&ComparisonNode{Position: 17, Key: "d", Operator: "==", Value: "4"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "nested or within or is flattened",
+			s:    "a==1,(b==2,c==3)",
+			want: &OrNode{
+				Position: 0,
+				Children: []Node{
+					&ComparisonNode{Position: 0, Key: "a", Operator: "==", Value: "1"},
+					&ComparisonNode{Position: 6, Key: "b", Operator: "==", Value: "2"},
+					&ComparisonNode{Position: 11, Key: "c", Operator: "==", Value: "3"},
+				},
+			},
+		},
+		{
+			name: "multibyte characters",
+			s:    "a=='ä',b==1",
+			want: &OrNode{
+				Position: 0,
+				Children: []Node{
+					&ComparisonNode{Position: 0, Key: "a", Operator: "==", Value: "'ä'"},
+					&ComparisonNode{Position: 8, Key: "b", Operator: "==", Value: "1"},
+				},
+			},
+		},
+		{
+			name:    "missing operator",
+			s:       "a",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			s:       "a==",
+			wantErr: true,
+		},
+		{
+			name:    "empty parentheses",
+			s:       "a==1;()",
+			wantErr: true,
+		},
+		{
+			name:    "parentheses mismatch",
+			s:       "a==1;(b==1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
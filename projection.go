@@ -0,0 +1,154 @@
+package rsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Projection is a parsed projection expression like name,address(city,zip),
+// containing the resolved keys of the selected fields, e.g. name, address.city and address.zip.
+type Projection []string
+
+// ParseProjection parses the given projection expression, a comma separated list of keys.
+// Fields of nested documents can be grouped in parentheses, e.g. address(city,zip).
+// Keys are resolved and validated like in Process, using their full path (e.g. address.city),
+// the process options for keys and errors are applied.
+func (parser *Parser) ParseProjection(s string, options ...func(*ProcessOptions) error) (Projection, error) {
+	return parser.ParseProjectionContext(context.Background(), s, options...)
+}
+
+// ParseProjectionContext works like ParseProjection. The given context is passed
+// to the parser's FieldMapper when resolving keys.
+func (parser *Parser) ParseProjectionContext(ctx context.Context, s string, options ...func(*ProcessOptions) error) (Projection, error) {
+	opts, err := newProcessOptions(s, options...)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	state := processState{
+		collect: opts.collectErrors,
+	}
+	if i := findUnmatchedParenthesis(s); i >= 0 {
+		state.report(newError(i, "parentheses mismatch"))
+		return nil, state.err()
+	}
+	var res Projection
+	parser.parseProjection(ctx, s, 0, "", &opts, &state, &res)
+	if err := state.err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// parseProjection parses the given part of a projection expression, offset being
+// its position within the whole expression and prefix the path of the enclosing group.
+// Resolved keys are added to res, errors are added to the state.
+func (parser *Parser) parseProjection(ctx context.Context, s string, offset int, prefix string, opts *ProcessOptions, state *processState, res *Projection) {
+	if strings.TrimSpace(s) == "" {
+		state.report(newError(offset, "missing key in projection"))
+		return
+	}
+	locations, err := findParts(s, -1, ",")
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			state.report(newError(offset+e.Pos, "%s", e.Msg))
+		} else {
+			state.report(newError(offset, "%w", err))
+		}
+		return
+	}
+	for _, loc := range locations {
+		part := s[loc[0]:loc[1]]
+		pos := offset + loc[0] + len(part) - len(strings.TrimLeft(part, " \t"))
+		part = strings.TrimSpace(part)
+		key, group := part, ""
+		if i := strings.Index(part, "("); i >= 0 {
+			if !strings.HasSuffix(part, ")") {
+				state.report(newError(pos+i, "unexpected content after group"))
+				if state.stopped() {
+					return
+				}
+				continue
+			}
+			key, group = strings.TrimRight(part[:i], " \t"), part[i+1:len(part)-1]
+			if strings.TrimSpace(group) == "" {
+				state.report(newError(pos+i, "empty group"))
+				if state.stopped() {
+					return
+				}
+				continue
+			}
+			if key != "" && !strings.ContainsAny(key, " \t=!;)'\"") {
+				parser.parseProjection(ctx, group, pos+i+1, prefix+key+".", opts, state, res)
+				if state.stopped() {
+					return
+				}
+				continue
+			}
+		}
+		if key == "" || strings.ContainsAny(key, " \t=!;()'\"") {
+			state.report(newError(pos, "missing or invalid key in projection"))
+			if state.stopped() {
+				return
+			}
+			continue
+		}
+		resolved, ok := parser.resolveKey(ctx, prefix+key, pos, opts, state)
+		if state.stopped() {
+			return
+		}
+		if !ok {
+			continue
+		}
+		if other, ok := res.conflict(resolved); ok {
+			state.report(newError(pos, "key '%s' conflicts with key '%s' in projection", resolved, other))
+			if state.stopped() {
+				return
+			}
+			continue
+		}
+		*res = append(*res, resolved)
+	}
+}
+
+// conflict checks if the given key equals or overlaps with a key of the projection,
+// e.g. address and address.city.
+func (p Projection) conflict(key string) (string, bool) {
+	for _, k := range p {
+		if k == key || strings.HasPrefix(key, k+".") || strings.HasPrefix(k, key+".") {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Mongo returns the projection as mongodb projection document,
+// e.g. { "name": 1, "address.city": 1 }.
+func (p Projection) Mongo() string {
+	if len(p) == 0 {
+		return "{ }"
+	}
+	fields := make([]string, 0, len(p))
+	for _, k := range p {
+		fields = append(fields, fmt.Sprintf(`"%s": 1`, k))
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// SQL returns the projection as list of sql columns, e.g. "name", "address"."city".
+// An empty projection selects all columns (*).
+func (p Projection) SQL() string {
+	if len(p) == 0 {
+		return "*"
+	}
+	columns := make([]string, 0, len(p))
+	for _, k := range p {
+		columns = append(columns, sqlIdentifier(k))
+	}
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,135 @@
+package rsql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParser_ParseProjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		options []func(*ProcessOptions) error
+		want    Projection
+		wantErr bool
+		wantPos []int
+	}{
+		{
+			name: "empty",
+			s:    "",
+		},
+		{
+			name: "fields",
+			s:    "name, id",
+			want: Projection{"name", "_id"},
+		},
+		{
+			name: "groups",
+			s:    "name,address(city, zip,geo(lat,lng)),tags",
+			want: Projection{"name", "address.city", "address.zip", "address.geo.lat", "address.geo.lng", "tags"},
+		},
+		{
+			name:    "allowed keys",
+			s:       "name,address(city)",
+			options: []func(*ProcessOptions) error{SetAllowedKeys([]string{"name", "address.city"})},
+			want:    Projection{"name", "address.city"},
+		},
+		{
+			name:    "key not allowed",
+			s:       "name,address(city,zip)",
+			options: []func(*ProcessOptions) error{SetAllowedKeys([]string{"name", "address.city"})},
+			wantErr: true,
+			wantPos: []int{18},
+		},
+		{
+			name:    "conflicting keys",
+			s:       "address,address(city)",
+			wantErr: true,
+			wantPos: []int{16},
+		},
+		{
+			name:    "empty group",
+			s:       "address()",
+			wantErr: true,
+			wantPos: []int{7},
+		},
+		{
+			name:    "missing key",
+			s:       "name,(city)",
+			wantErr: true,
+			wantPos: []int{5},
+		},
+		{
+			name:    "unmatched parenthesis",
+			s:       "address(city",
+			wantErr: true,
+			wantPos: []int{7},
+		},
+		{
+			name:    "content after group",
+			s:       "address(city)zip",
+			wantErr: true,
+			wantPos: []int{7},
+		},
+		{
+			name:    "collect errors",
+			s:       "a=b,address(,zip),x y",
+			options: []func(*ProcessOptions) error{SetCollectErrors(true)},
+			wantErr: true,
+			wantPos: []int{0, 12, 18},
+		},
+	}
+	parser, err := NewParser(Mongo(), WithKeyTransformers(func(s string) string {
+		if s == "id" {
+			return "_id"
+		}
+		return s
+	}))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseProjection(tt.s, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProjection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProjection() got = %v, want %v", got, tt.want)
+			}
+			if tt.wantPos == nil {
+				return
+			}
+			var pos []int
+			var errs ErrorList
+			var e *Error
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					pos = append(pos, e.Pos)
+				}
+			} else if errors.As(err, &e) {
+				pos = append(pos, e.Pos)
+			}
+			if !reflect.DeepEqual(pos, tt.wantPos) {
+				t.Errorf("ParseProjection() error positions = %v, want %v (%v)", pos, tt.wantPos, err)
+			}
+		})
+	}
+}
+
+func TestProjection_Format(t *testing.T) {
+	p := Projection{"name", "address.city"}
+	if got, want := p.Mongo(), `{ "name": 1, "address.city": 1 }`; got != want {
+		t.Errorf("Mongo() got = %v, want %v", got, want)
+	}
+	if got, want := p.SQL(), `"name", "address"."city"`; got != want {
+		t.Errorf("SQL() got = %v, want %v", got, want)
+	}
+	if got := (Projection{}).Mongo(); got != "{ }" {
+		t.Errorf("Mongo() got = %v, want { }", got)
+	}
+	if got := (Projection{}).SQL(); got != "*" {
+		t.Errorf("SQL() got = %v, want *", got)
+	}
+}
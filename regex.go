@@ -0,0 +1,89 @@
+package rsql
+
+import (
+	"fmt"
+	"regexp/syntax"
+)
+
+// RegexPolicy defines which regular expressions are accepted
+// as values of the =regex= operator. Regular expressions
+// always need to be valid according to the syntax of go's regexp package.
+type RegexPolicy struct {
+	// MaxLength is the maximum length of a regular expression.
+	// A value of 0 means no limit.
+	MaxLength int
+	// AllowNestedQuantifiers allows nested quantifiers like (a+)+,
+	// which can cause catastrophic backtracking in
+	// PCRE based databases like mongodb.
+	AllowNestedQuantifiers bool
+}
+
+// defaultRegexPolicy is the policy used if no other policy is set.
+var defaultRegexPolicy = RegexPolicy{
+	MaxLength: 256,
+}
+
+// WithRegexPolicy sets the policy for values of the =regex= operator.
+func WithRegexPolicy(policy RegexPolicy) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if policy.MaxLength < 0 {
+			return fmt.Errorf("maximum length of regular expressions must not be negative")
+		}
+		parser.regexPolicy = policy
+		return nil
+	}
+}
+
+// regexValidator returns a value transformer which checks
+// the values of the =regex= operator using the parser's policy.
+func regexValidator(parser *Parser) func(key, operator string, values []string) ([]string, error) {
+	return func(key, operator string, values []string) ([]string, error) {
+		if operator != "=regex=" {
+			return values, nil
+		}
+		for _, v := range values {
+			if err := parser.regexPolicy.validate(trimQuotes(v)); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+}
+
+// validate checks the given regular expression against the policy.
+func (policy RegexPolicy) validate(pattern string) error {
+	if policy.MaxLength > 0 && len(pattern) > policy.MaxLength {
+		return fmt.Errorf("regular expression must not be longer than %d characters", policy.MaxLength)
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if !policy.AllowNestedQuantifiers && hasNestedQuantifier(re, false) {
+		return fmt.Errorf("regular expression must not contain nested quantifiers")
+	}
+	return nil
+}
+
+// hasNestedQuantifier checks if the given regular expression contains
+// a repetition within another repetition, e.g. (a+)+ or (a*b?)*.
+// The quantified argument states if re is part of a repetition.
+func hasNestedQuantifier(re *syntax.Regexp, quantified bool) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if re.Op == syntax.OpRepeat && re.Max == re.Min {
+			// fixed number of repetitions like a{3}
+			break
+		}
+		if quantified {
+			return true
+		}
+		quantified = true
+	}
+	for _, sub := range re.Sub {
+		if hasNestedQuantifier(sub, quantified) {
+			return true
+		}
+	}
+	return false
+}
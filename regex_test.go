@@ -0,0 +1,113 @@
+package rsql
+
+import (
+	"testing"
+)
+
+func TestRegexPolicy_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RegexPolicy
+		pattern string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			policy:  defaultRegexPolicy,
+			pattern: `^Jo(hn|e)\s\d+$`,
+		},
+		{
+			name:    "invalid",
+			policy:  defaultRegexPolicy,
+			pattern: `^Jo(hn`,
+			wantErr: true,
+		},
+		{
+			name:    "backreference",
+			policy:  defaultRegexPolicy,
+			pattern: `(a)\1`,
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			policy:  RegexPolicy{MaxLength: 3},
+			pattern: `abcd`,
+			wantErr: true,
+		},
+		{
+			name:    "no length limit",
+			policy:  RegexPolicy{},
+			pattern: `abcdefghijklmnopqrstuvwxyz`,
+		},
+		{
+			name:    "nested quantifiers",
+			policy:  defaultRegexPolicy,
+			pattern: `^(a+)+$`,
+			wantErr: true,
+		},
+		{
+			name:    "nested quantifiers in alternation",
+			policy:  defaultRegexPolicy,
+			pattern: `(x|a*)*`,
+			wantErr: true,
+		},
+		{
+			name:    "fixed repetition within quantifier",
+			policy:  defaultRegexPolicy,
+			pattern: `(a{3})+`,
+		},
+		{
+			name:    "allowed nested quantifiers",
+			policy:  RegexPolicy{AllowNestedQuantifiers: true},
+			pattern: `^(a+)+$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(tt.pattern); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessRegexPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []func(*Parser) error
+		s       string
+		wantErr bool
+	}{
+		{
+			name:    "default policy",
+			options: []func(*Parser) error{Mongo()},
+			s:       `name=regex="^(a+)+$"`,
+			wantErr: true,
+		},
+		{
+			name: "custom policy",
+			options: []func(*Parser) error{
+				Mongo(),
+				WithRegexPolicy(RegexPolicy{AllowNestedQuantifiers: true}),
+			},
+			s: `name=regex="^(a+)+$"`,
+		},
+		{
+			name:    "sql",
+			options: []func(*Parser) error{SQL()},
+			s:       `name=regex="^(a"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewParser(tt.options...)
+			if err != nil {
+				t.Fatalf("error while creating parser: %s", err)
+			}
+			if _, err := parser.Process(tt.s); (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
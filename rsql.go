@@ -1,9 +1,13 @@
 package rsql
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // specialEncode is the map for encoding
@@ -20,6 +24,12 @@ var specialEncode = map[string]string{
 // regex to match Operator within operation
 var reOperator = regexp.MustCompile(`([!=])[^=()]*=`)
 
+// regex to match identifier within operation, before the equal or expression mark
+var reKey = regexp.MustCompile(`^[^=!]+`)
+
+// regex to match value within the operation, after the equal sign
+var reValue = regexp.MustCompile(`[^=]+$`)
+
 // Operator represents a query Operator.
 // It defines the Operator itself, the mongodb representation
 // of the Operator and if it is a list Operator or not.
@@ -31,16 +41,34 @@ type Operator struct {
 
 // Parser represents a RSQL parser.
 type Parser struct {
-	operators       []Operator
-	andFormatter    func(ss []string) string
-	orFormatter     func(ss []string) string
-	keyTransformers []func(s string) string
+	operators         []Operator
+	andFormatter      func(ss []string) string
+	orFormatter       func(ss []string) string
+	keyTransformers   []func(s string) string
+	rewriters         []func(Node) (Node, error)
+	valueTransformers []func(key, operator string, values []string) ([]string, error)
+	fieldMapper       FieldMapper
+	cache             *cache
+	regexPolicy       RegexPolicy
+	dates             *DateOptions
+	dateFormatter     func(t time.Time) string
+	valueFormatter    func(v interface{}) (string, error)
+	// binder replaces values marked by the formatters with the result of bind
+	// for their typed values or writes them as literals if bind is nil.
+	binder      func(s string, bind func(arg interface{}) string) string
+	placeholder func(n int) string
+	functions   map[string]Function
+	matchers    map[string]MatchFunc
+	// elemKey formats keys within sub-queries of the =elem= operator.
+	elemKey func(key string) string
 }
 
 // NewParser returns a new rsql server.
 func NewParser(options ...func(*Parser) error) (*Parser, error) {
 	// create parser
-	var parser = Parser{}
+	var parser = Parser{
+		regexPolicy: defaultRegexPolicy,
+	}
 	// run functional options
 	for _, op := range options {
 		err := op(&parser)
@@ -65,13 +93,19 @@ func Mongo() func(parser *Parser) error {
 			{
 				"==",
 				func(key, value string) string {
-					return fmt.Sprintf(`{ "%s": %s }`, key, value)
+					if parts, ok := splitWildcards(value); ok {
+						return fmt.Sprintf(`{ "%s": { "$regex": %s } }`, key, mongoRegex(parts))
+					}
+					return fmt.Sprintf(`{ "%s": %s }`, key, unescapeWildcards(value))
 				},
 			},
 			{
 				"!=",
 				func(key, value string) string {
-					return fmt.Sprintf(`{ "%s": { "$ne": %s } }`, key, value)
+					if parts, ok := splitWildcards(value); ok {
+						return fmt.Sprintf(`{ "%s": { "$not": { "$regex": %s } } }`, key, mongoRegex(parts))
+					}
+					return fmt.Sprintf(`{ "%s": { "$ne": %s } }`, key, unescapeWildcards(value))
 				},
 			},
 			{
@@ -114,8 +148,135 @@ func Mongo() func(parser *Parser) error {
 					return fmt.Sprintf(`{ "%s": { "$nin": %s } }`, key, value)
 				},
 			},
+			{
+				"=like=",
+				func(key, value string) string {
+					parts, _ := splitWildcards(value)
+					return fmt.Sprintf(`{ "%s": { "$regex": %s } }`, key, mongoRegex(parts))
+				},
+			},
+			{
+				"=ilike=",
+				func(key, value string) string {
+					parts, _ := splitWildcards(value)
+					return fmt.Sprintf(`{ "%s": { "$regex": %s, "$options": "i" } }`, key, mongoRegex(parts))
+				},
+			},
+			{
+				"=regex=",
+				func(key, value string) string {
+					b, _ := json.Marshal(trimQuotes(value))
+					return fmt.Sprintf(`{ "%s": { "$regex": %s } }`, key, b)
+				},
+			},
+			{
+				"=null=",
+				func(key, value string) string {
+					if isTrue(value) {
+						return fmt.Sprintf(`{ "%s": null }`, key)
+					}
+					return fmt.Sprintf(`{ "%s": { "$ne": null } }`, key)
+				},
+			},
+			{
+				"=exists=",
+				func(key, value string) string {
+					return fmt.Sprintf(`{ "%s": { "$exists": %t } }`, key, isTrue(value))
+				},
+			},
+			{
+				"=isempty=",
+				func(key, value string) string {
+					if isTrue(value) {
+						return fmt.Sprintf(`{ "%s": { "$in": [ null, "", [ ] ] } }`, key)
+					}
+					return fmt.Sprintf(`{ "%s": { "$nin": [ null, "", [ ] ] } }`, key)
+				},
+			},
+			{
+				"=between=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`{ "%s": { "$gte": %s, "$lte": %s } }`, key, values[0], values[1])
+				},
+			},
+			{
+				"=notbetween=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`{ "$or": [ { "%s": { "$lt": %s } }, { "%s": { "$gt": %s } } ] }`, key, values[0], key, values[1])
+				},
+			},
+			{
+				"=elem=",
+				func(key, value string) string {
+					return fmt.Sprintf(`{ "%s": { "$elemMatch": %s } }`, key, value)
+				},
+			},
+			{
+				"=size=",
+				func(key, value string) string {
+					return fmt.Sprintf(`{ "%s": { "$size": %s } }`, key, value)
+				},
+			},
+			{
+				"=all=",
+				func(key, value string) string {
+					// remove parentheses
+					value = strings.TrimSuffix(strings.TrimPrefix(value, "("), ")")
+					return fmt.Sprintf(`{ "%s": { "$all": [ %s ] } }`, key, value)
+				},
+			},
+			{
+				"=near=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					near := fmt.Sprintf(`"$geometry": { "type": "Point", "coordinates": %s }`, mongoPosition(values[0], values[1]))
+					if len(values) > 2 {
+						near += fmt.Sprintf(`, "$maxDistance": %s`, values[2])
+					}
+					if len(values) > 3 {
+						near += fmt.Sprintf(`, "$minDistance": %s`, values[3])
+					}
+					return fmt.Sprintf(`{ "%s": { "$near": { %s } } }`, key, near)
+				},
+			},
+			{
+				"=within=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					polygon := fmt.Sprintf(`{ "type": "Polygon", "coordinates": [ %s ] }`, mongoPositions(values, true))
+					return fmt.Sprintf(`{ "%s": { "$geoWithin": { "$geometry": %s } } }`, key, polygon)
+				},
+			},
+			{
+				"=intersects=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`{ "%s": { "$geoIntersects": { "$geometry": %s } } }`, key, mongoGeometry(values))
+				},
+			},
+		}
+		parser.addOperators(operators...)
+		parser.valueTransformers = append(parser.valueTransformers,
+			regexValidator(parser),
+			booleanValidator("=null=", "=exists=", "=isempty="),
+			rangeValidator("=between=", "=notbetween="),
+			sizeValidator("=size="),
+			geoValidator(),
+		)
+		// date formatter, extended json
+		parser.dateFormatter = func(t time.Time) string {
+			return fmt.Sprintf(`{ "$date": "%s" }`, t.UTC().Format("2006-01-02T15:04:05.000Z"))
+		}
+		// value formatter for results of functions, extended json
+		parser.valueFormatter = func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+			return string(b), nil
 		}
-		parser.operators = append(parser.operators, operators...)
 		// AND formatter
 		parser.andFormatter = func(ss []string) string {
 			if len(ss) > 1 {
@@ -140,7 +301,9 @@ func Mongo() func(parser *Parser) error {
 	}
 }
 
-// WithOperator adds custom operators to the parser
+// WithOperator adds custom operators to the parser.
+// Operators replace already added ones with the same token, e.g. the defaults
+// of Mongo() or SQL(), so custom operators need to be added after them.
 func WithOperators(operators ...Operator) func(parser *Parser) error {
 	return func(parser *Parser) error {
 		for _, o := range operators {
@@ -148,11 +311,29 @@ func WithOperators(operators ...Operator) func(parser *Parser) error {
 				return fmt.Errorf("invalid Operator '%s' as it does not match regex `(!|=)[^=]*=`", o.Operator)
 			}
 		}
-		parser.operators = append(parser.operators, operators...)
+		parser.addOperators(operators...)
 		return nil
 	}
 }
 
+// addOperators adds the given operators to the parser,
+// replacing already added ones with the same token.
+func (parser *Parser) addOperators(operators ...Operator) {
+	for _, o := range operators {
+		replaced := false
+		for i, existing := range parser.operators {
+			if existing.Operator == o.Operator {
+				parser.operators[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			parser.operators = append(parser.operators, o)
+		}
+	}
+}
+
 // WithKeyTransformers adds functions to alter key names in any way.
 func WithKeyTransformers(transformers ...func(string) string) func(parser *Parser) error {
 	return func(parser *Parser) error {
@@ -161,10 +342,113 @@ func WithKeyTransformers(transformers ...func(string) string) func(parser *Parse
 	}
 }
 
+// WithValueTransformers adds functions to alter values in any way.
+// Every transformer receives the (transformed) key, the operator and the value(s)
+// of an operation. List values like (1,2,3) are passed as separate values.
+// If a transformer returns an error, processing is aborted.
+func WithValueTransformers(transformers ...func(key, operator string, values []string) ([]string, error)) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		parser.valueTransformers = append(parser.valueTransformers, transformers...)
+		return nil
+	}
+}
+
+// WithFieldMapper sets the FieldMapper which is used to resolve keys
+// to their backend keys. The mapper runs after the key transformers.
+func WithFieldMapper(mapper FieldMapper) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if mapper == nil {
+			return fmt.Errorf("field mapper must not be nil")
+		}
+		parser.fieldMapper = mapper
+		return nil
+	}
+}
+
+// WithCache enables caching of the results of Process for the given number
+// of distinct queries. The least recently used results are evicted
+// if the cache is full. As the results are cached by query string and
+// process options, the cache must not be used if the result depends on the
+// context passed to ProcessContext, e.g. because of the parser's FieldMapper.
+func WithCache(size int) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if size <= 0 {
+			return fmt.Errorf("cache size must be greater than 0")
+		}
+		parser.cache = newCache(size)
+		return nil
+	}
+}
+
 // ProcessOptions contains options for the parser's Process function.
 type ProcessOptions struct {
-	allowedKeys   []string
-	forbiddenKeys []string
+	allowedKeys      []string
+	forbiddenKeys    []string
+	allowedOperators map[string][]string
+	maxLength        int
+	maxDepth         int
+	maxComparisons   int
+	maxListItems     int
+	maxDistinctKeys  int
+	collectErrors    bool
+}
+
+// processState keeps track of the state while processing a string.
+type processState struct {
+	comparisons int
+	keys        map[string]bool
+	errs        ErrorList
+	// collect defines if processing continues after errors.
+	collect bool
+	// aborted is set if processing needs to be stopped, e.g. because a limit was exceeded.
+	aborted bool
+	// volatile is set if the result depends on the current time.
+	volatile bool
+	// scope is the comparison of the =elem= operator currently processed.
+	scope *comparison
+	// scopes contains the enclosing comparisons of nested =elem= operators.
+	scopes []*comparison
+}
+
+// enter starts processing the sub-query of the given comparison.
+func (state *processState) enter(c *comparison) {
+	state.scopes = append(state.scopes, state.scope)
+	state.scope = c
+}
+
+// leave stops processing the current sub-query.
+func (state *processState) leave() {
+	state.scope = state.scopes[len(state.scopes)-1]
+	state.scopes = state.scopes[:len(state.scopes)-1]
+}
+
+// report adds the given error.
+func (state *processState) report(err *Error) {
+	state.errs = append(state.errs, err)
+}
+
+// abort adds the given error and stops processing.
+func (state *processState) abort(err *Error) {
+	state.report(err)
+	state.aborted = true
+}
+
+// err returns the first error or, if errors are collected,
+// all errors sorted by position.
+func (state *processState) err() error {
+	if len(state.errs) == 0 {
+		return nil
+	}
+	if !state.collect {
+		return state.errs[0]
+	}
+	state.errs.sortByPos()
+	return state.errs
+}
+
+// stopped returns true if processing should not be continued.
+func (state *processState) stopped() bool {
+	return state.aborted || !state.collect && len(state.errs) > 0
 }
 
 // SetAllowedKeys set's the keys which can be used for querying.
@@ -183,6 +467,27 @@ func SetForbiddenKeys(keys []string) func(opts *ProcessOptions) error {
 	}
 }
 
+// SetAllowedOperators set's the operators which can be used for the given keys.
+// The map's keys are the keys to restrict, the values the operators which are allowed
+// for the given key. Keys which are not contained in the map can be used with any operator.
+func SetAllowedOperators(operators map[string][]string) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		opts.allowedOperators = operators
+		return nil
+	}
+}
+
+// SetCollectErrors defines if processing should continue after an error
+// to report all errors within the query at once. If set to true,
+// Process returns an ErrorList containing all errors sorted by position.
+// Processing is always stopped if a limit is exceeded.
+func SetCollectErrors(collect bool) func(opts *ProcessOptions) error {
+	return func(opts *ProcessOptions) error {
+		opts.collectErrors = collect
+		return nil
+	}
+}
+
 // containsString checks if a given slice of strings contains a given string.
 func containsString(ss []string, s string) bool {
 	for _, x := range ss {
@@ -193,94 +498,385 @@ func containsString(ss []string, s string) bool {
 	return false
 }
 
-// Process takes the given string and processes it using parser's operators.
-func (parser *Parser) Process(s string, options ...func(*ProcessOptions) error) (string, error) {
-	// set process options
+// newProcessOptions applies the given process options
+// and checks the length of the given string.
+func newProcessOptions(s string, options ...func(*ProcessOptions) error) (ProcessOptions, error) {
 	opts := ProcessOptions{}
 	for _, op := range options {
 		err := op(&opts)
 		if err != nil {
-			return "", fmt.Errorf("setting process option failed: %w", err)
+			return opts, fmt.Errorf("setting process option failed: %w", err)
 		}
 	}
-	// regex to match identifier within operation, before the equal or expression mark
-	var reKey = regexp.MustCompile(`^[^=!]+`)
-	// regex to match value within the operation, after the equal sign
-	var reValue = regexp.MustCompile(`[^=]+$`)
-	// get ORs
-	locations, err := findORs(s, -1)
+	if opts.maxLength > 0 && len(s) > opts.maxLength {
+		return opts, &LimitError{Limit: LimitLength, Max: opts.maxLength}
+	}
+	return opts, nil
+}
+
+// Process takes the given string and processes it using parser's operators.
+func (parser *Parser) Process(s string, options ...func(*ProcessOptions) error) (string, error) {
+	return parser.ProcessContext(context.Background(), s, options...)
+}
+
+// ProcessContext works like Process. The given context is passed
+// to the parser's FieldMapper when resolving keys.
+func (parser *Parser) ProcessContext(ctx context.Context, s string, options ...func(*ProcessOptions) error) (string, error) {
+	res, err := parser.processCached(ctx, s, options...)
+	if err != nil || parser.binder == nil {
+		return res, err
+	}
+	return parser.binder(res, nil), nil
+}
+
+// ProcessArgs works like Process, but values are not written as literals.
+// Instead, they are replaced by placeholders and returned as arguments,
+// e.g. to be passed to database/sql. Dates are bound as time.Time.
+// The parser's operators need to support arguments, like the ones of SQL().
+func (parser *Parser) ProcessArgs(s string, options ...func(*ProcessOptions) error) (string, []interface{}, error) {
+	return parser.ProcessArgsContext(context.Background(), s, options...)
+}
+
+// ProcessArgsContext works like ProcessArgs. The given context is passed
+// to the parser's FieldMapper when resolving keys.
+func (parser *Parser) ProcessArgsContext(ctx context.Context, s string, options ...func(*ProcessOptions) error) (string, []interface{}, error) {
+	if parser.binder == nil {
+		return "", nil, errors.New("the parser's operators do not support arguments")
+	}
+	res, err := parser.processCached(ctx, s, options...)
 	if err != nil {
-		return "", fmt.Errorf("unable to find ORs: %w", err)
-	}
-	var ors []string
-	for _, loc := range locations {
-		start, end := loc[0], loc[1]
-		content := s[start:end]
-		// handle ANDs
-		locs, err := findANDs(content, -1)
-		if err != nil {
-			return "", fmt.Errorf("unable to find ANDs: %w", err)
-		}
-		var ands []string
-		for _, l := range locs {
-			start, end = l[0], l[1]
-			content := content[start:end]
-			// handle parentheses
-			parentheses, err := findOuterParentheses(content, -1)
-			if err != nil {
-				return "", fmt.Errorf("unable to find parentheses: %w", err)
+		return "", nil, err
+	}
+	args := []interface{}{}
+	res = parser.binder(res, func(arg interface{}) string {
+		args = append(args, arg)
+		if parser.placeholder == nil {
+			return "?"
+		}
+		return parser.placeholder(len(args))
+	})
+	return res, args, nil
+}
+
+// processCached processes the given string using the parser's cache, if any.
+func (parser *Parser) processCached(ctx context.Context, s string, options ...func(*ProcessOptions) error) (string, error) {
+	opts, err := newProcessOptions(s, options...)
+	if err != nil {
+		return "", err
+	}
+	if parser.cache == nil {
+		res, _, err := parser.processString(ctx, s, &opts)
+		return res, err
+	}
+	key := cacheKey(s, &opts)
+	if entry, ok := parser.cache.get(key); ok {
+		return entry.res, entry.err
+	}
+	res, volatile, err := parser.processString(ctx, s, &opts)
+	if !volatile {
+		parser.cache.add(key, res, err)
+	}
+	return res, err
+}
+
+// Join combines the given processed queries by the logical AND operator
+// using the parser's formatter, e.g. to combine filters processed separately.
+// Empty queries are ignored, joining no queries results in an empty string.
+func (parser *Parser) Join(queries ...string) string {
+	ss := make([]string, 0, len(queries))
+	for _, q := range queries {
+		if q != "" {
+			ss = append(ss, q)
+		}
+	}
+	switch len(ss) {
+	case 0:
+		return ""
+	case 1:
+		return ss[0]
+	}
+	return parser.andFormatter(ss)
+}
+
+// processString parses and processes the given string.
+// The returned volatile value states if the result depends
+// on the current time, e.g. because of relative dates.
+func (parser *Parser) processString(ctx context.Context, s string, opts *ProcessOptions) (string, bool, error) {
+	// parse
+	parsing := parseState{
+		maxDepth: opts.maxDepth,
+	}
+	node := parse(s, 0, 0, &parsing)
+	state := processState{
+		keys:    make(map[string]bool),
+		errs:    parsing.errs,
+		collect: opts.collectErrors,
+		aborted: parsing.aborted,
+	}
+	// rewrite
+	if !state.stopped() {
+		node = parser.rewrite(node, &state)
+	}
+	// process
+	var res string
+	if !state.stopped() {
+		res = parser.process(ctx, node, opts, &state)
+	}
+	if err := state.err(); err != nil {
+		return "", state.volatile, err
+	}
+	return res, state.volatile, nil
+}
+
+// process validates the given node using the given options
+// and formats it using the parser's formatters.
+// Errors are added to the state.
+func (parser *Parser) process(ctx context.Context, node Node, opts *ProcessOptions, state *processState) string {
+	switch n := node.(type) {
+	case *OrNode:
+		ss := make([]string, 0, len(n.Children))
+		for _, child := range n.Children {
+			ss = append(ss, parser.process(ctx, child, opts, state))
+			if state.stopped() {
+				return ""
 			}
-			for _, p := range parentheses {
-				start, end := p[0], p[1]
-				content := content[start+1 : end]
-				// handle nested
-				replacement, err := parser.Process(content)
-				if err != nil {
-					return "", err
-				}
-				ands = append(ands, replacement)
+		}
+		return parser.orFormatter(ss)
+	case *AndNode:
+		ss := make([]string, 0, len(n.Children))
+		for _, child := range n.Children {
+			ss = append(ss, parser.process(ctx, child, opts, state))
+			if state.stopped() {
+				return ""
 			}
-			if len(parentheses) > 0 {
-				// location is already fully handled
+		}
+		return parser.andFormatter(ss)
+	case *ComparisonNode:
+		return parser.processComparison(ctx, n, opts, state)
+	case nil:
+		// empty query
+		return parser.orFormatter(nil)
+	}
+	state.report(newError(node.Pos(), "unsupported node type %T", node))
+	return ""
+}
+
+// patternOperators contains the default operators whose values are patterns,
+// neither function calls nor date literals within them are resolved.
+var patternOperators = []string{"=regex=", "=like=", "=ilike="}
+
+// comparison is a validated comparison with its resolved key and transformed values.
+type comparison struct {
+	// key is the resolved key, relative to the enclosing =elem= operator if any.
+	key string
+	// path and rawPath are the full resolved and given keys.
+	path      string
+	rawPath   string
+	operator  string
+	value     string
+	values    []string
+	isList    bool
+	formatter func(key, value string) string
+}
+
+// processComparison validates and formats the given comparison.
+func (parser *Parser) processComparison(ctx context.Context, n *ComparisonNode, opts *ProcessOptions, state *processState) string {
+	c, ok := parser.resolveComparison(ctx, n, opts, state)
+	if !ok {
+		return ""
+	}
+	key, value, values, isList := c.key, c.value, c.values, c.isList
+	if parser.elemKey != nil && state.scope != nil {
+		key = parser.elemKey(key)
+	}
+	// process sub-query
+	if c.operator == "=elem=" {
+		node, ok := parser.parseElem(n, c, opts, state)
+		if !ok {
+			return ""
+		}
+		state.enter(c)
+		sub := parser.process(ctx, node, opts, state)
+		state.leave()
+		if state.stopped() {
+			return ""
+		}
+		return c.formatter(key, sub)
+	}
+	// call functions, patterns are kept as they are
+	if parser.functions != nil && !containsString(patternOperators, c.operator) {
+		var called bool
+		for i, v := range values {
+			if !isFunctionCall(v) {
 				continue
 			}
-			// if no parentheses, it should be an operation
-			operator := reOperator.FindString(content)
-			key := reKey.FindString(content)
-			value := reValue.FindString(content)
-			if operator == "" || key == "" || value == "" {
-				return "", fmt.Errorf("incomplete operation '%s'", content)
+			res, err := parser.evaluate(v)
+			if err == nil {
+				values[i], err = parser.formatValue(res)
 			}
-			// run key transformers
-			for _, t := range parser.keyTransformers {
-				key = t(key)
-			}
-			// check if key is allowed
-			if containsString(opts.forbiddenKeys, key) {
-				return "", fmt.Errorf("given key '%s' is not allowed", key)
+			if err != nil {
+				state.report(invalidValueError(n, key, err))
+				return ""
 			}
-			if len(opts.allowedKeys) > 0 && !containsString(opts.allowedKeys, key) {
-				return "", fmt.Errorf("given key '%s' is not allowed", key)
+			called = true
+		}
+		if called {
+			value = joinValues(values, isList)
+			state.volatile = true
+		}
+	}
+	// resolve date literals, patterns are kept as they are
+	if parser.dates != nil && !containsString(patternOperators, c.operator) {
+		if err := parser.dates.checkRange(c.operator, values); err != nil {
+			state.report(invalidValueError(n, key, err))
+			return ""
+		}
+		var resolved bool
+		for i, v := range values {
+			t, relative, ok, err := parser.dates.resolve(v)
+			if err != nil {
+				state.report(invalidValueError(n, key, err))
+				return ""
 			}
-			// parse operation
-			var res string
-			for _, op := range parser.operators {
-				if operator == op.Operator {
-					res = op.Formatter(key, value)
-					break
-				}
+			if !ok {
+				continue
 			}
-			if res == "" {
-				return "", fmt.Errorf("unknown operator '%s' in '%s'", operator, content)
+			values[i] = parser.formatDate(t)
+			resolved = true
+			state.volatile = state.volatile || relative
+		}
+		if resolved {
+			value = joinValues(values, isList)
+		}
+	}
+	return c.formatter(key, value)
+}
+
+// resolveComparison validates the given comparison, resolves its key
+// and runs the value transformers. Errors are added to the state.
+func (parser *Parser) resolveComparison(ctx context.Context, n *ComparisonNode, opts *ProcessOptions, state *processState) (*comparison, bool) {
+	state.comparisons++
+	if opts.maxComparisons > 0 && state.comparisons > opts.maxComparisons {
+		state.abort(newError(n.Pos(), "%w", &LimitError{Limit: LimitComparisons, Max: opts.maxComparisons}))
+		return nil, false
+	}
+	numErrs := len(state.errs)
+	key, operator, value := n.Key, n.Operator, n.Value
+	if state.scope != nil {
+		key = state.scope.rawPath + "." + key
+	}
+	rawPath := key
+	key, ok := parser.resolveKey(ctx, key, n.Pos(), opts, state)
+	if !ok {
+		return nil, false
+	}
+	// check if operator is allowed for key
+	if ops, ok := opts.allowedOperators[key]; ok && !containsString(ops, operator) {
+		err := newError(n.operatorPos(), "operator '%s' is not allowed for key '%s', allowed operators are: %s", operator, key, strings.Join(ops, " "))
+		state.report(withSuggestions(err, suggest(operator, ops)))
+	}
+	// find operator
+	var formatter func(key, value string) string
+	for _, op := range parser.operators {
+		if operator == op.Operator {
+			formatter = op.Formatter
+			break
+		}
+	}
+	if formatter == nil {
+		var candidates []string
+		for _, op := range parser.operators {
+			candidates = append(candidates, op.Operator)
+		}
+		err := newError(n.operatorPos(), "unknown operator '%s'", operator)
+		state.report(withSuggestions(err, suggest(operator, candidates)))
+	}
+	if len(state.errs) > numErrs {
+		return nil, false
+	}
+	state.keys[key] = true
+	if opts.maxDistinctKeys > 0 && len(state.keys) > opts.maxDistinctKeys {
+		state.abort(newError(n.Pos(), "%w", &LimitError{Limit: LimitDistinctKeys, Max: opts.maxDistinctKeys}))
+		return nil, false
+	}
+	path := key
+	if state.scope != nil {
+		if !strings.HasPrefix(key, state.scope.path+".") {
+			state.report(newError(n.Pos(), "given key '%s' can not be used within '%s'", key, state.scope.path))
+			return nil, false
+		}
+		key = strings.TrimPrefix(key, state.scope.path+".")
+	}
+	values, isList := []string{value}, false
+	if operator != "=elem=" {
+		values, isList = splitValues(value)
+	}
+	if opts.maxListItems > 0 && isList && len(values) > opts.maxListItems {
+		state.abort(newError(n.valuePos(), "%w", &LimitError{Limit: LimitListItems, Max: opts.maxListItems}))
+		return nil, false
+	}
+	// run value transformers
+	if len(parser.valueTransformers) > 0 {
+		var err error
+		for _, t := range parser.valueTransformers {
+			values, err = t(key, operator, values)
+			if err != nil {
+				state.report(invalidValueError(n, key, err))
+				return nil, false
 			}
-			ands = append(ands, res)
 		}
-		// replacement for AND-block
-		replacement := parser.andFormatter(ands)
-		ors = append(ors, replacement)
+		value = joinValues(values, isList)
 	}
-	// replace OR-block and return
-	return parser.orFormatter(ors), nil
+	return &comparison{
+		key:       key,
+		path:      path,
+		rawPath:   rawPath,
+		operator:  operator,
+		value:     value,
+		values:    values,
+		isList:    isList,
+		formatter: formatter,
+	}, true
+}
+
+// resolveKey runs the key transformers and the field mapper on the given key
+// and checks if the resulting key is allowed. Errors are added to the state,
+// the returned bool is false if the key could not be resolved.
+func (parser *Parser) resolveKey(ctx context.Context, key string, pos int, opts *ProcessOptions, state *processState) (string, bool) {
+	// run key transformers
+	for _, t := range parser.keyTransformers {
+		key = t(key)
+	}
+	// resolve backend key
+	if parser.fieldMapper != nil {
+		resolved, err := parser.fieldMapper.Resolve(ctx, key)
+		if err != nil {
+			state.report(newError(pos, "given key '%s' is not allowed: %w", key, err))
+			return "", false
+		}
+		key = resolved
+	}
+	// check if key is allowed
+	if containsString(opts.forbiddenKeys, key) {
+		state.report(newError(pos, "given key '%s' is not allowed", key))
+	} else if len(opts.allowedKeys) > 0 && !containsString(opts.allowedKeys, key) {
+		err := newError(pos, "given key '%s' is not allowed", key)
+		state.report(withSuggestions(err, suggest(key, opts.allowedKeys)))
+	}
+	return key, true
+}
+
+// invalidValueError returns an error about an invalid value of the given comparison.
+// Suggestions of the given error are kept.
+func invalidValueError(n *ComparisonNode, key string, err error) *Error {
+	e := newError(n.valuePos(), "invalid value for key '%s': %w", key, err)
+	var inner *Error
+	if errors.As(err, &inner) {
+		// the position is the one of the value
+		e.Msg = strings.Replace(e.Msg, inner.Error(), inner.Msg, 1)
+		e.Suggestions = inner.Suggestions
+	}
+	return e
 }
 
 // encodeSpecial encodes all the special strings
@@ -301,6 +897,35 @@ func decodeSpecial(s string) string {
 	return s
 }
 
+// splitValues splits the given value into its list items.
+// If the value is not a list in parentheses, the value itself is returned.
+// The second return value states if the value was a list.
+func splitValues(value string) ([]string, bool) {
+	if len(value) < 2 || value[0] != '(' || value[len(value)-1] != ')' {
+		return []string{value}, false
+	}
+	inner := value[1 : len(value)-1]
+	locs, err := findParts(inner, -1, ",")
+	if err != nil {
+		// not a valid list, treat it as a single value
+		return []string{value}, false
+	}
+	values := make([]string, 0, len(locs))
+	for _, loc := range locs {
+		values = append(values, inner[loc[0]:loc[1]])
+	}
+	return values, true
+}
+
+// joinValues is the counterpart of splitValues.
+// It joins the given values to a list in parentheses if isList is true.
+func joinValues(values []string, isList bool) string {
+	if isList {
+		return "(" + strings.Join(values, ",") + ")"
+	}
+	return strings.Join(values, ",")
+}
+
 // findParts finds the locations of separated blocks while considering parentheses.
 // If n is greater than 0, n parts (from the left) are returned at most.
 func findParts(s string, n int, separators ...string) ([][]int, error) {
@@ -313,16 +938,15 @@ func findParts(s string, n int, separators ...string) ([][]int, error) {
 	}
 	for _, sep := range separators {
 		if s[0:1] == sep {
-			return nil, fmt.Errorf("given string starts with a separators")
+			return nil, &Error{Pos: 0, Msg: fmt.Sprintf("unexpected '%s'", sep)}
 		}
 		if s[len(s)-1:] == sep {
-			return nil, fmt.Errorf("given string ends with a separators")
+			return nil, &Error{Pos: len(s) - 1, Msg: fmt.Sprintf("unexpected '%s'", sep)}
 		}
 	}
 	var res [][]int
 	var start, par, found int
-	runes := []rune(s)
-	for i, r := range runes {
+	for i, r := range s {
 		c := string(r)
 		// parentheses
 		if c == "(" {
@@ -331,7 +955,7 @@ func findParts(s string, n int, separators ...string) ([][]int, error) {
 		if c == ")" {
 			par--
 			if par < 0 {
-				return nil, fmt.Errorf("parentheses mismatch")
+				return nil, &Error{Pos: i, Msg: "parentheses mismatch"}
 			}
 		}
 		// while par for parentheses is not zero,
@@ -378,14 +1002,13 @@ func findANDs(s string, n int) ([][]int, error) {
 // Every entry will have two integers, the first one providing the index of the
 // opening parentheses, the second one the index of the closing parentheses.
 func findOuterParentheses(s string, n int) ([][]int, error) {
-	if strings.Count(s, "(") != strings.Count(s, ")") {
-		return nil, fmt.Errorf("number of opening and closing parentheses dont match in string '%s'", s)
+	if i := findUnmatchedParenthesis(s); i >= 0 {
+		return nil, &Error{Pos: i, Msg: "parentheses mismatch"}
 	}
 	var res [][]int
 	var start, par, nested, found int
 	var op bool
-	runes := []rune(s)
-	for i, r := range runes {
+	for i, r := range s {
 		c := string(r)
 		// start or part of operator
 		if c == "=" || c == "!" {
@@ -411,7 +1034,7 @@ func findOuterParentheses(s string, n int) ([][]int, error) {
 			if nested > 0 {
 				nested--
 				if nested < 0 {
-					return nil, fmt.Errorf("parentheses mismatch")
+					return nil, &Error{Pos: i, Msg: "parentheses mismatch"}
 				}
 				continue
 			} else {
@@ -422,7 +1045,7 @@ func findOuterParentheses(s string, n int) ([][]int, error) {
 				continue
 			}
 			if par < 0 {
-				return nil, fmt.Errorf("parentheses mismatch")
+				return nil, &Error{Pos: i, Msg: "parentheses mismatch"}
 			}
 			// found outer parentheses
 			found++
@@ -438,3 +1061,24 @@ func findOuterParentheses(s string, n int) ([][]int, error) {
 	}
 	return res, nil
 }
+
+// findUnmatchedParenthesis returns the index of the first parenthesis
+// without counterpart in the given string or -1 if all parentheses match.
+func findUnmatchedParenthesis(s string) int {
+	var open []int
+	for i, r := range s {
+		switch r {
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) == 0 {
+				return i
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return open[0]
+	}
+	return -1
+}
@@ -1,6 +1,8 @@
 package rsql
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -192,13 +194,15 @@ func Test_findOuterParentheses(t *testing.T) {
 
 func TestParser_ProcessMongo(t *testing.T) {
 	tests := []struct {
-		name            string
-		s               string
-		options         []func(*ProcessOptions) error
-		customOperators []Operator
-		keyTransformers []func(s string) string
-		want            string
-		wantErr         bool
+		name              string
+		s                 string
+		options           []func(*ProcessOptions) error
+		customOperators   []Operator
+		keyTransformers   []func(s string) string
+		valueTransformers []func(key, operator string, values []string) ([]string, error)
+		fieldMapper       FieldMapper
+		want              string
+		wantErr           bool
 	}{
 		{
 			name: "empty",
@@ -245,6 +249,101 @@ func TestParser_ProcessMongo(t *testing.T) {
 			s:    "a=out=(1,2,3)",
 			want: `{ "a": { "$nin": 1,2,3 } }`,
 		},
+		{
+			name: "== with wildcard",
+			s:    `name=="Jo*"`,
+			want: `{ "name": { "$regex": "^Jo.*$" } }`,
+		},
+		{
+			name: "== with escaped wildcard",
+			s:    `name=="Jo\*"`,
+			want: `{ "name": "Jo*" }`,
+		},
+		{
+			name: "== with wildcard and regex characters",
+			s:    `name==*(a.b)*`,
+			want: `{ "name": { "$regex": "^.*\\(a\\.b\\).*$" } }`,
+		},
+		{
+			name: "!= with wildcard",
+			s:    `name!='*son'`,
+			want: `{ "name": { "$not": { "$regex": "^.*son$" } } }`,
+		},
+		{
+			name: "=like=",
+			s:    `name=like="Jo*"`,
+			want: `{ "name": { "$regex": "^Jo.*$" } }`,
+		},
+		{
+			name: "=ilike=",
+			s:    `name=ilike=jo*.`,
+			want: `{ "name": { "$regex": "^jo.*\\.$", "$options": "i" } }`,
+		},
+		{
+			name: "=regex=",
+			s:    `name=regex="^J(o|a)\d"`,
+			want: `{ "name": { "$regex": "^J(o|a)\\d" } }`,
+		},
+		{
+			name:    "=regex= invalid",
+			s:       `name=regex="^J(o"`,
+			wantErr: true,
+		},
+		{
+			name: "=null=true",
+			s:    `a=null=true`,
+			want: `{ "a": null }`,
+		},
+		{
+			name: "=null=false",
+			s:    `a=null=FALSE`,
+			want: `{ "a": { "$ne": null } }`,
+		},
+		{
+			name: "=exists=true",
+			s:    `a=exists=true`,
+			want: `{ "a": { "$exists": true } }`,
+		},
+		{
+			name: "=exists=false",
+			s:    `a=exists=false`,
+			want: `{ "a": { "$exists": false } }`,
+		},
+		{
+			name: "=isempty=true",
+			s:    `a=isempty=true`,
+			want: `{ "a": { "$in": [ null, "", [ ] ] } }`,
+		},
+		{
+			name: "=isempty=false",
+			s:    `a=isempty=false`,
+			want: `{ "a": { "$nin": [ null, "", [ ] ] } }`,
+		},
+		{
+			name:    "=exists= invalid value",
+			s:       `a=exists=1`,
+			wantErr: true,
+		},
+		{
+			name: "=between=",
+			s:    `price=between=(10,20)`,
+			want: `{ "price": { "$gte": 10, "$lte": 20 } }`,
+		},
+		{
+			name: "=notbetween=",
+			s:    `price=notbetween=(10,20)`,
+			want: `{ "$or": [ { "price": { "$lt": 10 } }, { "price": { "$gt": 20 } } ] }`,
+		},
+		{
+			name:    "=between= with swapped bounds",
+			s:       `price=between=(20,10)`,
+			wantErr: true,
+		},
+		{
+			name:    "=between= without list",
+			s:       `price=between=10`,
+			wantErr: true,
+		},
 		{
 			name: "(a==1)",
 			s:    "(a==1)",
@@ -285,6 +384,19 @@ func TestParser_ProcessMongo(t *testing.T) {
 			s:    "(a==1,b==1);(c==1,d==2)",
 			want: `{ "$and": [ { "$or": [ { "a": 1 }, { "b": 1 } ] }, { "$or": [ { "c": 1 }, { "d": 2 } ] } ] }`,
 		},
+		{
+			name: "custom operator overrides default operator",
+			s:    "a=exists=true",
+			customOperators: []Operator{
+				{
+					Operator: "=exists=",
+					Formatter: func(key, value string) string {
+						return fmt.Sprintf(`{ "%s": { "$exists": %s, "$ne": null } }`, key, value)
+					},
+				},
+			},
+			want: `{ "a": { "$exists": true, "$ne": null } }`,
+		},
 		{
 			name: "custom operator: =ex=",
 			s:    "a=ex=true",
@@ -355,6 +467,48 @@ func TestParser_ProcessMongo(t *testing.T) {
 			wantErr: false,
 			want:    `{ "a": 1 }`,
 		},
+		{
+			name: "key not allowed in parentheses",
+			s:    "b==1;(a==1,b==2)",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"b"}),
+			},
+			wantErr: true,
+			want:    "",
+		},
+		{
+			name: "operator allowed",
+			s:    "email=in=('a','b');createdAt=gt=5",
+			options: []func(*ProcessOptions) error{
+				SetAllowedOperators(map[string][]string{
+					"email":     {"==", "=in="},
+					"createdAt": {"=gt=", "=ge=", "=lt=", "=le="},
+				}),
+			},
+			want: `{ "$and": [ { "email": { "$in": 'a','b' } }, { "createdAt": { "$gt": 5 } } ] }`,
+		},
+		{
+			name: "operator not allowed",
+			s:    "a==1;email!='a'",
+			options: []func(*ProcessOptions) error{
+				SetAllowedOperators(map[string][]string{
+					"email": {"==", "=in="},
+				}),
+			},
+			wantErr: true,
+			want:    "",
+		},
+		{
+			name: "operator not allowed in parentheses",
+			s:    "a==1;(b==1,createdAt==5)",
+			options: []func(*ProcessOptions) error{
+				SetAllowedOperators(map[string][]string{
+					"createdAt": {"=gt=", "=ge=", "=lt=", "=le="},
+				}),
+			},
+			wantErr: true,
+			want:    "",
+		},
 		{
 			name: "uppercase key transformer",
 			s:    "a==1",
@@ -366,6 +520,90 @@ func TestParser_ProcessMongo(t *testing.T) {
 			wantErr: false,
 			want:    `{ "A": 1 }`,
 		},
+		{
+			name: "value transformer",
+			s:    `_id=="abc"`,
+			valueTransformers: []func(key, operator string, values []string) ([]string, error){
+				func(key, operator string, values []string) ([]string, error) {
+					if key != "_id" {
+						return values, nil
+					}
+					for i, v := range values {
+						values[i] = fmt.Sprintf("ObjectId(%s)", v)
+					}
+					return values, nil
+				},
+			},
+			want: `{ "_id": ObjectId("abc") }`,
+		},
+		{
+			name: "value transformer on list",
+			s:    `a=in=(1,2,3)`,
+			valueTransformers: []func(key, operator string, values []string) ([]string, error){
+				func(key, operator string, values []string) ([]string, error) {
+					return append(values, "4"), nil
+				},
+			},
+			want: `{ "a": { "$in": 1,2,3,4 } }`,
+		},
+		{
+			name: "value transformer receives transformed key",
+			s:    "a==1",
+			keyTransformers: []func(s string) string{
+				func(s string) string {
+					return strings.ToUpper(s)
+				},
+			},
+			valueTransformers: []func(key, operator string, values []string) ([]string, error){
+				func(key, operator string, values []string) ([]string, error) {
+					if key != "A" || operator != "==" {
+						return nil, fmt.Errorf("unexpected key '%s' or operator '%s'", key, operator)
+					}
+					return values, nil
+				},
+			},
+			want: `{ "A": 1 }`,
+		},
+		{
+			name: "field mapper",
+			s:    "createdAt=gt=5;created=lt=10",
+			fieldMapper: FieldMap{
+				Fields:  map[string]string{"createdAt": "created_at"},
+				Aliases: map[string]string{"created": "createdAt"},
+			},
+			want: `{ "$and": [ { "created_at": { "$gt": 5 } }, { "created_at": { "$lt": 10 } } ] }`,
+		},
+		{
+			name: "field mapper with allowed keys",
+			s:    "createdAt=gt=5",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"created_at"}),
+			},
+			fieldMapper: FieldMap{
+				Fields: map[string]string{"createdAt": "created_at"},
+			},
+			want: `{ "created_at": { "$gt": 5 } }`,
+		},
+		{
+			name: "field mapper unknown key",
+			s:    "a==1",
+			fieldMapper: FieldMap{
+				Fields: map[string]string{"b": ""},
+			},
+			wantErr: true,
+			want:    "",
+		},
+		{
+			name: "value transformer error",
+			s:    "a==1",
+			valueTransformers: []func(key, operator string, values []string) ([]string, error){
+				func(key, operator string, values []string) ([]string, error) {
+					return nil, fmt.Errorf("invalid value")
+				},
+			},
+			wantErr: true,
+			want:    "",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -373,6 +611,10 @@ func TestParser_ProcessMongo(t *testing.T) {
 			opts = append(opts, Mongo())
 			opts = append(opts, WithOperators(tt.customOperators...))
 			opts = append(opts, WithKeyTransformers(tt.keyTransformers...))
+			opts = append(opts, WithValueTransformers(tt.valueTransformers...))
+			if tt.fieldMapper != nil {
+				opts = append(opts, WithFieldMapper(tt.fieldMapper))
+			}
 			parser, err := NewParser(opts...)
 			if err != nil {
 				t.Fatalf("error while creating parser: %s", err)
@@ -389,6 +631,141 @@ func TestParser_ProcessMongo(t *testing.T) {
 	}
 }
 
+func TestParser_ProcessContext(t *testing.T) {
+	type langKey struct{}
+	localized := map[string]map[string]string{
+		"de": {"name": "name", "preis": "price"},
+		"en": {"name": "name", "price": "price"},
+	}
+	mapper := FieldMapperFunc(func(ctx context.Context, key string) (string, error) {
+		lang, _ := ctx.Value(langKey{}).(string)
+		if k, ok := localized[lang][key]; ok {
+			return k, nil
+		}
+		return "", fmt.Errorf("%w '%s'", ErrUnknownField, key)
+	})
+	parser, err := NewParser(Mongo(), WithFieldMapper(mapper))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	tests := []struct {
+		name    string
+		lang    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "german",
+			lang: "de",
+			s:    "preis=lt=10",
+			want: `{ "price": { "$lt": 10 } }`,
+		},
+		{
+			name: "english",
+			lang: "en",
+			s:    "price=lt=10",
+			want: `{ "price": { "$lt": 10 } }`,
+		},
+		{
+			name:    "wrong language",
+			lang:    "en",
+			s:       "preis=lt=10",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), langKey{}, tt.lang)
+			got, err := parser.ProcessContext(ctx, tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ProcessContext() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !errors.Is(err, ErrUnknownField) {
+				t.Errorf("ProcessContext() error = %v, want ErrUnknownField", err)
+			}
+			if got != tt.want {
+				t.Errorf("ProcessContext() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessCollectErrors(t *testing.T) {
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	tests := []struct {
+		name    string
+		s       string
+		options []func(*ProcessOptions) error
+		wantPos []int
+	}{
+		{
+			name: "no errors",
+			s:    "a==1;b==2",
+		},
+		{
+			name: "first error only",
+			s:    "x==1;a=foo=1;(b==1,y==2)",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"a", "b"}),
+			},
+			wantPos: []int{0},
+		},
+		{
+			name: "all errors",
+			s:    "x==1;a=foo=1;(b==1,y==2)",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"a", "b"}),
+				SetCollectErrors(true),
+			},
+			wantPos: []int{0, 6, 19},
+		},
+		{
+			name: "syntax and key errors",
+			s:    "a==;b=in=(1,2),(c)",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"a"}),
+				SetCollectErrors(true),
+			},
+			wantPos: []int{3, 4, 17},
+		},
+		{
+			name: "stop at limit",
+			s:    "x==1;a==1;b==1;y==1",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"a", "b"}),
+				SetMaxComparisons(2),
+				SetCollectErrors(true),
+			},
+			wantPos: []int{0, 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Process(tt.s, tt.options...)
+			var gotPos []int
+			switch e := err.(type) {
+			case nil:
+			case *Error:
+				gotPos = []int{e.Pos}
+			case ErrorList:
+				for _, x := range e {
+					gotPos = append(gotPos, x.Pos)
+				}
+			default:
+				t.Fatalf("Process() error = %v of unexpected type %T", err, err)
+			}
+			if !reflect.DeepEqual(gotPos, tt.wantPos) {
+				t.Errorf("Process() error positions = %v, want %v (error: %v)", gotPos, tt.wantPos, err)
+			}
+		})
+	}
+}
+
 func Test_findParts(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -0,0 +1,119 @@
+package rsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SortField is a field of a sort expression.
+type SortField struct {
+	Key        string
+	Descending bool
+}
+
+// Sort is a parsed sort expression like name,-createdAt.
+type Sort []SortField
+
+// ParseSort parses the given sort expression, a comma separated list of keys.
+// Keys prefixed with a minus are sorted in descending order, keys without
+// prefix or with a plus in ascending order. Keys are resolved and validated
+// like in Process, the process options for keys and errors are applied.
+func (parser *Parser) ParseSort(s string, options ...func(*ProcessOptions) error) (Sort, error) {
+	return parser.ParseSortContext(context.Background(), s, options...)
+}
+
+// ParseSortContext works like ParseSort. The given context is passed
+// to the parser's FieldMapper when resolving keys.
+func (parser *Parser) ParseSortContext(ctx context.Context, s string, options ...func(*ProcessOptions) error) (Sort, error) {
+	opts, err := newProcessOptions(s, options...)
+	if err != nil {
+		return nil, err
+	}
+	state := processState{
+		collect: opts.collectErrors,
+	}
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var res Sort
+	seen := make(map[string]bool)
+	pos := 0
+	for _, part := range strings.Split(s, ",") {
+		start := pos
+		pos += len(part) + 1
+		// skip leading whitespace
+		trimmed := strings.TrimLeft(part, " \t")
+		start += len(part) - len(trimmed)
+		key := strings.TrimRight(trimmed, " \t")
+		field := SortField{}
+		switch {
+		case strings.HasPrefix(key, "-"):
+			field.Descending = true
+			key = key[1:]
+			start++
+		case strings.HasPrefix(key, "+"):
+			key = key[1:]
+			start++
+		}
+		if key == "" || strings.ContainsAny(key, " \t=!;()'\"") || strings.HasPrefix(key, "-") || strings.HasPrefix(key, "+") {
+			state.report(newError(start, "missing or invalid key in sort expression"))
+			if state.stopped() {
+				break
+			}
+			continue
+		}
+		resolved, ok := parser.resolveKey(ctx, key, start, &opts, &state)
+		if state.stopped() {
+			break
+		}
+		if !ok {
+			continue
+		}
+		if seen[resolved] {
+			state.report(newError(start, "duplicate key '%s' in sort expression", resolved))
+			if state.stopped() {
+				break
+			}
+			continue
+		}
+		seen[resolved] = true
+		field.Key = resolved
+		res = append(res, field)
+	}
+	if err := state.err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Mongo returns the sort expression as mongodb sort document,
+// e.g. { "name": 1, "createdAt": -1 }.
+func (s Sort) Mongo() string {
+	if len(s) == 0 {
+		return "{ }"
+	}
+	fields := make([]string, 0, len(s))
+	for _, f := range s {
+		direction := 1
+		if f.Descending {
+			direction = -1
+		}
+		fields = append(fields, fmt.Sprintf(`"%s": %d`, f.Key, direction))
+	}
+	return "{ " + strings.Join(fields, ", ") + " }"
+}
+
+// SQL returns the sort expression as list for an sql ORDER BY clause,
+// e.g. "name" ASC, "createdAt" DESC.
+func (s Sort) SQL() string {
+	fields := make([]string, 0, len(s))
+	for _, f := range s {
+		direction := "ASC"
+		if f.Descending {
+			direction = "DESC"
+		}
+		fields = append(fields, sqlIdentifier(f.Key)+" "+direction)
+	}
+	return strings.Join(fields, ", ")
+}
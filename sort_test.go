@@ -0,0 +1,163 @@
+package rsql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParser_ParseSort(t *testing.T) {
+	tests := []struct {
+		name            string
+		s               string
+		options         []func(*ProcessOptions) error
+		want            Sort
+		wantErr         bool
+		wantPos         []int
+		wantSuggestions []string
+	}{
+		{
+			name: "empty",
+			s:    " ",
+		},
+		{
+			name: "directions",
+			s:    "name,-createdAt, +age",
+			want: Sort{{Key: "name"}, {Key: "createdAt", Descending: true}, {Key: "age"}},
+		},
+		{
+			name: "key transformers",
+			s:    "-id",
+			want: Sort{{Key: "_id", Descending: true}},
+		},
+		{
+			name:    "allowed keys",
+			s:       "name,-createdAt",
+			options: []func(*ProcessOptions) error{SetAllowedKeys([]string{"name", "createdAt"})},
+			want:    Sort{{Key: "name"}, {Key: "createdAt", Descending: true}},
+		},
+		{
+			name:            "key not allowed",
+			s:               "name,-cratedAt",
+			options:         []func(*ProcessOptions) error{SetAllowedKeys([]string{"name", "createdAt"})},
+			wantErr:         true,
+			wantPos:         []int{6},
+			wantSuggestions: []string{"createdAt"},
+		},
+		{
+			name:    "forbidden key",
+			s:       "password",
+			options: []func(*ProcessOptions) error{SetForbiddenKeys([]string{"password"})},
+			wantErr: true,
+			wantPos: []int{0},
+		},
+		{
+			name:    "missing key",
+			s:       "name,,age",
+			wantErr: true,
+			wantPos: []int{5},
+		},
+		{
+			name:    "invalid key",
+			s:       "--name",
+			wantErr: true,
+			wantPos: []int{1},
+		},
+		{
+			name:    "duplicate key",
+			s:       "name,-name",
+			wantErr: true,
+			wantPos: []int{6},
+		},
+		{
+			name:    "collect errors",
+			s:       "a==1,,name,b c",
+			options: []func(*ProcessOptions) error{SetCollectErrors(true)},
+			wantErr: true,
+			wantPos: []int{0, 5, 11},
+		},
+		{
+			name:    "max length",
+			s:       "name,age",
+			options: []func(*ProcessOptions) error{SetMaxLength(5)},
+			wantErr: true,
+		},
+	}
+	parser, err := NewParser(Mongo(), WithKeyTransformers(func(s string) string {
+		if s == "id" {
+			return "_id"
+		}
+		return s
+	}))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseSort(tt.s, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort() got = %v, want %v", got, tt.want)
+			}
+			if tt.wantPos == nil {
+				return
+			}
+			var errs ErrorList
+			var e *Error
+			if errors.As(err, &errs) {
+				var pos []int
+				for _, e := range errs {
+					pos = append(pos, e.Pos)
+				}
+				if !reflect.DeepEqual(pos, tt.wantPos) {
+					t.Errorf("ParseSort() error positions = %v, want %v", pos, tt.wantPos)
+				}
+			} else if errors.As(err, &e) {
+				if e.Pos != tt.wantPos[0] || !reflect.DeepEqual(e.Suggestions, tt.wantSuggestions) {
+					t.Errorf("ParseSort() error = %v at %d with suggestions %v, want %v with %v", e, e.Pos, e.Suggestions, tt.wantPos[0], tt.wantSuggestions)
+				}
+			} else {
+				t.Errorf("ParseSort() error = %v, want *Error", err)
+			}
+		})
+	}
+}
+
+func TestSort_Format(t *testing.T) {
+	tests := []struct {
+		sort      Sort
+		wantMongo string
+		wantSQL   string
+	}{
+		{
+			wantMongo: "{ }",
+		},
+		{
+			sort:      Sort{{Key: "name"}, {Key: "createdAt", Descending: true}},
+			wantMongo: `{ "name": 1, "createdAt": -1 }`,
+			wantSQL:   `"name" ASC, "createdAt" DESC`,
+		},
+		{
+			sort:      Sort{{Key: "user.name"}},
+			wantMongo: `{ "user.name": 1 }`,
+			wantSQL:   `"user"."name" ASC`,
+		},
+	}
+	for _, tt := range tests {
+		var keys []string
+		for _, f := range tt.sort {
+			keys = append(keys, f.Key)
+		}
+		t.Run(strings.Join(keys, ","), func(t *testing.T) {
+			if got := tt.sort.Mongo(); got != tt.wantMongo {
+				t.Errorf("Mongo() got = %v, want %v", got, tt.wantMongo)
+			}
+			if got := tt.sort.SQL(); got != tt.wantSQL {
+				t.Errorf("SQL() got = %v, want %v", got, tt.wantSQL)
+			}
+		})
+	}
+}
@@ -0,0 +1,432 @@
+package rsql
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// regex to match numeric values
+var reNumber = regexp.MustCompile(`^-?\d+(\.\d+)?([eE][-+]?\d+)?$`)
+
+// sqlArgPrefix marks values within formatted sql conditions, which are written as literals
+// by Process and bound as arguments by ProcessArgs. It contains a random nonce,
+// so marked values cannot be given within queries.
+var sqlArgPrefix = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "\x00arg." + hex.EncodeToString(b) + "."
+}()
+
+// kinds of marked sql values
+const (
+	sqlKindString = 's'
+	sqlKindNumber = 'n'
+	sqlKindBool   = 'b'
+	sqlKindTime   = 't'
+)
+
+// SQL adds the default sql operators to the parser.
+// The resulting string can be used as WHERE clause in standard sql
+// databases like PostgreSQL or SQLite.
+// Keys are quoted as identifiers, values are quoted as
+// string literals unless they are numbers, booleans or null.
+// Using ProcessArgs, values are bound as arguments instead, see WithPlaceholders.
+func SQL() func(parser *Parser) error {
+	return func(parser *Parser) error {
+		// comparison returns a formatter for the given sql operator
+		comparison := func(op string) func(key, value string) string {
+			return func(key, value string) string {
+				return fmt.Sprintf(`%s %s %s`, sqlKey(key, value), op, sqlValue(value))
+			}
+		}
+		equal, notEqual := comparison("="), comparison("<>")
+		// operators
+		var operators = []Operator{
+			{
+				"==",
+				func(key, value string) string {
+					// like mongodb, null matches null values
+					if strings.EqualFold(value, "null") {
+						return fmt.Sprintf(`%s IS NULL`, sqlKey(key))
+					}
+					if parts, ok := splitWildcards(value); ok {
+						return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, sqlKey(key), sqlArg(sqlKindString, sqlLike(parts)))
+					}
+					return equal(key, value)
+				},
+			},
+			{
+				"!=",
+				func(key, value string) string {
+					if strings.EqualFold(value, "null") {
+						return fmt.Sprintf(`%s IS NOT NULL`, sqlKey(key))
+					}
+					if parts, ok := splitWildcards(value); ok {
+						return fmt.Sprintf(`%s NOT LIKE %s ESCAPE '\'`, sqlKey(key), sqlArg(sqlKindString, sqlLike(parts)))
+					}
+					return notEqual(key, value)
+				},
+			},
+			{
+				"=gt=",
+				comparison(">"),
+			},
+			{
+				"=ge=",
+				comparison(">="),
+			},
+			{
+				"=lt=",
+				comparison("<"),
+			},
+			{
+				"=le=",
+				comparison("<="),
+			},
+			{
+				"=in=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`%s IN %s`, sqlKey(key, values...), sqlList(values))
+				},
+			},
+			{
+				"=out=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`%s NOT IN %s`, sqlKey(key, values...), sqlList(values))
+				},
+			},
+			{
+				"=like=",
+				func(key, value string) string {
+					parts, _ := splitWildcards(value)
+					return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, sqlKey(key), sqlArg(sqlKindString, sqlLike(parts)))
+				},
+			},
+			{
+				"=ilike=",
+				func(key, value string) string {
+					parts, _ := splitWildcards(value)
+					return fmt.Sprintf(`LOWER(%s) LIKE LOWER(%s) ESCAPE '\'`, sqlKey(key), sqlArg(sqlKindString, sqlLike(parts)))
+				},
+			},
+			{
+				"=regex=",
+				func(key, value string) string {
+					// PostgreSQL syntax
+					return fmt.Sprintf(`%s ~ %s`, sqlKey(key), sqlArg(sqlKindString, trimQuotes(value)))
+				},
+			},
+			{
+				"=null=",
+				func(key, value string) string {
+					if isTrue(value) {
+						return fmt.Sprintf(`%s IS NULL`, sqlKey(key))
+					}
+					return fmt.Sprintf(`%s IS NOT NULL`, sqlKey(key))
+				},
+			},
+			{
+				"=exists=",
+				func(key, value string) string {
+					// columns always exist, so a missing value is null
+					if isTrue(value) {
+						return fmt.Sprintf(`%s IS NOT NULL`, sqlKey(key))
+					}
+					return fmt.Sprintf(`%s IS NULL`, sqlKey(key))
+				},
+			},
+			{
+				"=isempty=",
+				func(key, value string) string {
+					if isTrue(value) {
+						return fmt.Sprintf(`(%s IS NULL OR %s = '')`, sqlKey(key), sqlKey(key))
+					}
+					return fmt.Sprintf(`(%s IS NOT NULL AND %s <> '')`, sqlKey(key), sqlKey(key))
+				},
+			},
+			{
+				"=elem=",
+				func(key, value string) string {
+					// PostgreSQL syntax, elements of a jsonb array
+					return fmt.Sprintf(`EXISTS (SELECT 1 FROM jsonb_array_elements(%s) AS "elem" WHERE %s)`, sqlJSON(key), value)
+				},
+			},
+			{
+				"=size=",
+				func(key, value string) string {
+					return fmt.Sprintf(`jsonb_array_length(%s) = %s`, sqlJSON(key), sqlValue(value))
+				},
+			},
+			{
+				"=all=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`%s @> jsonb_build_array%s`, sqlJSON(key), sqlList(values))
+				},
+			},
+			{
+				"=between=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`%s BETWEEN %s AND %s`, sqlKey(key, values...), sqlValue(values[0]), sqlValue(values[1]))
+				},
+			},
+			{
+				"=notbetween=",
+				func(key, value string) string {
+					values, _ := splitValues(value)
+					return fmt.Sprintf(`%s NOT BETWEEN %s AND %s`, sqlKey(key, values...), sqlValue(values[0]), sqlValue(values[1]))
+				},
+			},
+		}
+		parser.addOperators(operators...)
+		parser.valueTransformers = append(parser.valueTransformers,
+			regexValidator(parser),
+			booleanValidator("=null=", "=exists=", "=isempty="),
+			rangeValidator("=between=", "=notbetween="),
+			sizeValidator("=size="),
+		)
+		// date formatter
+		parser.dateFormatter = func(t time.Time) string {
+			return sqlArg(sqlKindTime, t.UTC().Format(time.RFC3339Nano))
+		}
+		// value formatter for results of functions
+		parser.valueFormatter = sqlLiteral
+		// marked values are written as literals or bound as arguments
+		parser.binder = sqlBind
+		// keys within =elem= sub-queries are fields of the array elements
+		parser.elemKey = func(key string) string {
+			return sqlElemPrefix + key
+		}
+		// AND formatter
+		parser.andFormatter = func(ss []string) string {
+			if len(ss) > 1 {
+				return fmt.Sprintf(`(%s)`, strings.Join(ss, " AND "))
+			}
+			if len(ss) == 0 {
+				return ""
+			}
+			return ss[0]
+		}
+		// OR formatter
+		parser.orFormatter = func(ss []string) string {
+			if len(ss) > 1 {
+				return fmt.Sprintf(`(%s)`, strings.Join(ss, " OR "))
+			}
+			if len(ss) == 0 {
+				return "1 = 1"
+			}
+			return ss[0]
+		}
+		return nil
+	}
+}
+
+// WithPlaceholders sets the function returning the placeholder of the nth argument (starting at 1)
+// within conditions returned by ProcessArgs, e.g. DollarPlaceholder. By default, ? is used.
+func WithPlaceholders(placeholder func(n int) string) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		if placeholder == nil {
+			return fmt.Errorf("placeholder function must not be nil")
+		}
+		parser.placeholder = placeholder
+		return nil
+	}
+}
+
+// DollarPlaceholder returns the placeholder $n, as used by PostgreSQL.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// sqlElemPrefix marks keys within =elem= sub-queries.
+const sqlElemPrefix = "\x00elem."
+
+// sqlKey returns the given key as sql expression. Keys within =elem= sub-queries
+// are extracted from the array element as text, which is cast to numeric,
+// boolean or timestamptz if all given values are numbers, booleans or dates.
+func sqlKey(key string, values ...string) string {
+	if !strings.HasPrefix(key, sqlElemPrefix) {
+		return sqlIdentifier(key)
+	}
+	expr := fmt.Sprintf(`"elem" #>> '%s'`, sqlPath(key))
+	numbers, booleans, dates := len(values) > 0, len(values) > 0, len(values) > 0
+	for _, v := range values {
+		numbers = numbers && reNumber.MatchString(v)
+		booleans = booleans && (strings.EqualFold(v, "true") || strings.EqualFold(v, "false"))
+		dates = dates && strings.HasPrefix(v, sqlArgPrefix+string(sqlKindTime))
+	}
+	switch {
+	case numbers:
+		return fmt.Sprintf(`(%s)::numeric`, expr)
+	case booleans:
+		return fmt.Sprintf(`(%s)::boolean`, expr)
+	case dates:
+		return fmt.Sprintf(`(%s)::timestamptz`, expr)
+	}
+	return fmt.Sprintf(`(%s)`, expr)
+}
+
+// sqlJSON returns the given key as jsonb expression.
+func sqlJSON(key string) string {
+	if !strings.HasPrefix(key, sqlElemPrefix) {
+		return sqlIdentifier(key)
+	}
+	return fmt.Sprintf(`("elem" #> '%s')`, sqlPath(key))
+}
+
+// sqlPath returns the given key within an =elem= sub-query as json path, e.g. {address,city}.
+func sqlPath(key string) string {
+	parts := strings.Split(strings.TrimPrefix(key, sqlElemPrefix), ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(strings.ReplaceAll(p, `\`, `\\`), `"`, `\"`) + `"`
+	}
+	return sqlEscape("{" + strings.Join(parts, ",") + "}")
+}
+
+// sqlIdentifier quotes the given key as sql identifier.
+// Dots separate the parts of a qualified identifier, e.g. table.column.
+func sqlIdentifier(key string) string {
+	parts := strings.Split(key, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// sqlValue returns the given value as marked sql value, see sqlArg.
+// Values which are marked already are returned as they are, null as literal.
+func sqlValue(value string) string {
+	if strings.HasPrefix(value, sqlArgPrefix) {
+		return value
+	}
+	if reNumber.MatchString(value) {
+		return sqlArg(sqlKindNumber, value)
+	}
+	switch strings.ToLower(value) {
+	case "true", "false":
+		return sqlArg(sqlKindBool, value)
+	case "null":
+		return "NULL"
+	}
+	return sqlArg(sqlKindString, unescape(trimQuotes(value)))
+}
+
+// sqlArg returns the given value of the given kind as marked sql value.
+// The value is hex encoded, so it cannot contain the end of the mark.
+func sqlArg(kind byte, value string) string {
+	return sqlArgPrefix + string(kind) + hex.EncodeToString([]byte(value)) + "\x00"
+}
+
+// sqlBind replaces the marked values within the given sql condition with the result
+// of bind for their typed values. If bind is nil, the values are written as sql literals.
+func sqlBind(s string, bind func(arg interface{}) string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, sqlArgPrefix)
+		if i < 0 {
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i+len(sqlArgPrefix):]
+		end := strings.IndexByte(s, 0)
+		raw, _ := hex.DecodeString(s[1:end])
+		kind, value := s[0], string(raw)
+		s = s[end+1:]
+		if bind != nil {
+			b.WriteString(bind(sqlArgValue(kind, value)))
+			continue
+		}
+		switch kind {
+		case sqlKindNumber:
+			b.WriteString(value)
+		case sqlKindBool:
+			b.WriteString(strings.ToUpper(value))
+		default:
+			b.WriteString("'" + sqlEscape(value) + "'")
+		}
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// sqlArgValue returns the typed argument for the given marked value.
+func sqlArgValue(kind byte, value string) interface{} {
+	switch kind {
+	case sqlKindNumber:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case sqlKindBool:
+		return strings.EqualFold(value, "true")
+	case sqlKindTime:
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t
+		}
+	}
+	return value
+}
+
+// sqlLiteral returns the given typed value as sql literal,
+// strings are returned as marked sql values, see sqlArg.
+func sqlLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return sqlArg(sqlKindString, v), nil
+	case fmt.Stringer:
+		return sqlArg(sqlKindString, v.String()), nil
+	}
+	return "", fmt.Errorf("unsupported sql value of type %T", v)
+}
+
+// sqlList returns the given values as sql list.
+func sqlList(values []string) string {
+	res := make([]string, len(values))
+	for i, v := range values {
+		res[i] = sqlValue(v)
+	}
+	return "(" + strings.Join(res, ", ") + ")"
+}
+
+// sqlEscape escapes single quotes for usage within a sql string literal.
+func sqlEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// unescape removes backslashes used to escape characters.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,254 @@
+package rsql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParser_ProcessSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			s:    "",
+			want: "1 = 1",
+		},
+		{
+			name: "==",
+			s:    "a==1",
+			want: `"a" = 1`,
+		},
+		{
+			name: "!=",
+			s:    `a!="x"`,
+			want: `"a" <> 'x'`,
+		},
+		{
+			name: "=gt=",
+			s:    "a=gt=1.5",
+			want: `"a" > 1.5`,
+		},
+		{
+			name: "=ge=",
+			s:    "a=ge=-1",
+			want: `"a" >= -1`,
+		},
+		{
+			name: "=lt=",
+			s:    "a=lt=1e3",
+			want: `"a" < 1e3`,
+		},
+		{
+			name: "=le=",
+			s:    "a=le=1",
+			want: `"a" <= 1`,
+		},
+		{
+			name: "=in=",
+			s:    "a=in=(1,'b',true)",
+			want: `"a" IN (1, 'b', TRUE)`,
+		},
+		{
+			name: "=out=",
+			s:    "a=out=(1,2,3)",
+			want: `"a" NOT IN (1, 2, 3)`,
+		},
+		{
+			name: "quoting",
+			s:    `a.b=="it's",c=="x\"y"`,
+			want: `("a"."b" = 'it''s' OR "c" = 'x"y')`,
+		},
+		{
+			name: "unquoted string",
+			s:    `a==1 OR TRUE`,
+			want: `"a" = '1 OR TRUE'`,
+		},
+		{
+			name: "nested",
+			s:    "(a==1;b==2),c=gt=5",
+			want: `(("a" = 1 AND "b" = 2) OR "c" > 5)`,
+		},
+		{
+			name: "wildcard",
+			s:    `name=="Jo*"`,
+			want: `"name" LIKE 'Jo%' ESCAPE '\'`,
+		},
+		{
+			name: "negated wildcard",
+			s:    `name!=*_x*`,
+			want: `"name" NOT LIKE '%\_x%' ESCAPE '\'`,
+		},
+		{
+			name: "=like=",
+			s:    `name=like="Jo*"`,
+			want: `"name" LIKE 'Jo%' ESCAPE '\'`,
+		},
+		{
+			name: "=ilike=",
+			s:    `name=ilike='jo_*'`,
+			want: `LOWER("name") LIKE LOWER('jo\_%') ESCAPE '\'`,
+		},
+		{
+			name: "=regex=",
+			s:    `name=regex="^J(o|a)'"`,
+			want: `"name" ~ '^J(o|a)'''`,
+		},
+		{
+			name: "== null",
+			s:    `a==null;b==NULL;c=="null"`,
+			want: `("a" IS NULL AND "b" IS NULL AND "c" = 'null')`,
+		},
+		{
+			name: "!= null",
+			s:    `a!=null;b!="null"`,
+			want: `("a" IS NOT NULL AND "b" <> 'null')`,
+		},
+		{
+			name: "=null=",
+			s:    `a=null=true;b=null=false`,
+			want: `("a" IS NULL AND "b" IS NOT NULL)`,
+		},
+		{
+			name: "=exists=",
+			s:    `a=exists=true;b=exists=false`,
+			want: `("a" IS NOT NULL AND "b" IS NULL)`,
+		},
+		{
+			name: "=isempty=",
+			s:    `a=isempty=true,b=isempty=false`,
+			want: `(("a" IS NULL OR "a" = '') OR ("b" IS NOT NULL AND "b" <> ''))`,
+		},
+		{
+			name:    "=null= invalid value",
+			s:       `a=null=(true,false)`,
+			wantErr: true,
+		},
+		{
+			name: "=between=",
+			s:    `price=between=(10,20)`,
+			want: `"price" BETWEEN 10 AND 20`,
+		},
+		{
+			name: "=notbetween=",
+			s:    `name=notbetween=("a","m")`,
+			want: `"name" NOT BETWEEN 'a' AND 'm'`,
+		},
+		{
+			name:    "=between= with three values",
+			s:       `price=between=(1,2,3)`,
+			wantErr: true,
+		},
+		{
+			name: "escaped asterisk",
+			s:    `name=="Jo\*"`,
+			want: `"name" = 'Jo*'`,
+		},
+	}
+	parser, err := NewParser(SQL())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.Process(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Process() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessArgsSQL(t *testing.T) {
+	day := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		s        string
+		want     string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "empty",
+			s:        "",
+			want:     "1 = 1",
+			wantArgs: []interface{}{},
+		},
+		{
+			name:     "values",
+			s:        `a==1;b!="it's";c=gt=1.5;d==true;e==null`,
+			want:     `("a" = $1 AND "b" <> $2 AND "c" > $3 AND "d" = $4 AND "e" IS NULL)`,
+			wantArgs: []interface{}{int64(1), "it's", 1.5, true},
+		},
+		{
+			name:     "list",
+			s:        `a=in=(x,2)`,
+			want:     `"a" IN ($1, $2)`,
+			wantArgs: []interface{}{"x", int64(2)},
+		},
+		{
+			name:     "patterns",
+			s:        `a==Jo*;b=ilike=*doe;c=regex="^[a-z]+$"`,
+			want:     `("a" LIKE $1 ESCAPE '\' AND LOWER("b") LIKE LOWER($2) ESCAPE '\' AND "c" ~ $3)`,
+			wantArgs: []interface{}{"Jo%", "%doe", "^[a-z]+$"},
+		},
+		{
+			name:     "dates",
+			s:        `createdAt=between=(2021-08-01,2021-08-02T12:00:00+02:00)`,
+			want:     `"createdAt" BETWEEN $1 AND $2`,
+			wantArgs: []interface{}{day, time.Date(2021, 8, 2, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "dates within sub-query",
+			s:        `items=elem=(addedAt=lt=2021-08-01;qty=size=2)`,
+			want:     `EXISTS (SELECT 1 FROM jsonb_array_elements("items") AS "elem" WHERE (("elem" #>> '{"addedAt"}')::timestamptz < $1 AND jsonb_array_length(("elem" #> '{"qty"}')) = $2))`,
+			wantArgs: []interface{}{day, int64(2)},
+		},
+	}
+	parser, err := NewParser(SQL(), WithDates(DateOptions{}), WithPlaceholders(DollarPlaceholder))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := parser.ProcessArgs(tt.s)
+			if err != nil {
+				t.Fatalf("ProcessArgs() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ProcessArgs() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("ProcessArgs() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+	// values are written as literals by Process
+	got, err := parser.Process(`createdAt=between=(2021-08-01,2021-08-02);name=="it's"`)
+	if want := `("createdAt" BETWEEN '2021-08-01T00:00:00Z' AND '2021-08-02T00:00:00Z' AND "name" = 'it''s')`; err != nil || got != want {
+		t.Errorf("Process() got = %v, %v, want %v", got, err, want)
+	}
+	// the default placeholder is ?
+	parser, err = NewParser(SQL())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	if got, _, _ := parser.ProcessArgs(`a==1;b==2`); got != `("a" = ? AND "b" = ?)` {
+		t.Errorf("ProcessArgs() got = %v", got)
+	}
+	// arguments need to be supported by the operators
+	parser, err = NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	if _, _, err := parser.ProcessArgs(`a==1`); err == nil {
+		t.Errorf("ProcessArgs() expected error for mongo parser")
+	}
+}
@@ -0,0 +1,153 @@
+package rsql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regex to match numbers within csv files, numbers with leading zeros (e.g. zip codes) are kept as strings
+var reCSVNumber = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][-+]?\d+)?$`)
+
+// FilterNDJSON reads newline delimited json documents from r and writes
+// the lines of the documents matching the query to w. Empty lines are skipped.
+// Documents are processed one by one, so only a single document is kept in memory.
+// The number of matching documents is returned.
+func (q *Query) FilterNDJSON(r io.Reader, w io.Writer) (int, error) {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	matched := 0
+	// documents matched before an error are still written
+	fail := func(err error) (int, error) {
+		bw.Flush()
+		return matched, err
+	}
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fail(err)
+		}
+		if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(trimmed))
+			dec.UseNumber()
+			var doc interface{}
+			if err := dec.Decode(&doc); err != nil {
+				return fail(fmt.Errorf("invalid document in line %d: %w", line, err))
+			}
+			ok, err := q.Match(doc)
+			if err != nil {
+				return fail(fmt.Errorf("evaluating document in line %d failed: %w", line, err))
+			}
+			if ok {
+				matched++
+				bw.Write(trimmed)
+				if err := bw.WriteByte('\n'); err != nil {
+					return matched, err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return matched, bw.Flush()
+}
+
+// FilterCSV reads csv records with a header row from r and writes the header
+// and the records matching the query to w. The header row defines the keys,
+// keys containing dots (e.g. address.city) define nested documents.
+// Types of values are inferred: empty values are null, true and false are booleans
+// and numbers without leading zeros are numbers, all other values are strings.
+// Records are processed one by one, so only a single record is kept in memory.
+// The number of matching records is returned.
+func (q *Query) FilterCSV(r io.Reader, w io.Writer) (int, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	keys := make([][]string, len(header))
+	for i, h := range header {
+		keys[i] = strings.Split(strings.TrimSpace(h), ".")
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+	matched := 0
+	// records matched before an error are still written
+	fail := func(err error) (int, error) {
+		cw.Flush()
+		return matched, err
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		doc := make(map[string]interface{}, len(record))
+		for i, v := range record {
+			setPath(doc, keys[i], csvValue(v))
+		}
+		ok, err := q.Match(doc)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return fail(fmt.Errorf("evaluating record in line %d failed: %w", line, err))
+		}
+		if ok {
+			matched++
+			if err := cw.Write(record); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	cw.Flush()
+	return matched, cw.Error()
+}
+
+// csvValue returns the typed value of the given csv value.
+func csvValue(s string) interface{} {
+	switch s {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if reCSVNumber.MatchString(s) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// setPath sets the value at the given path within the document, creating nested documents.
+func setPath(doc map[string]interface{}, path []string, value interface{}) {
+	for _, p := range path[:len(path)-1] {
+		child, ok := doc[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			doc[p] = child
+		}
+		doc = child
+	}
+	doc[path[len(path)-1]] = value
+}
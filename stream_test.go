@@ -0,0 +1,98 @@
+package rsql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQuery_FilterNDJSON(t *testing.T) {
+	input := `{"status": "A", "qty": 25}
+
+{"status": "B", "qty": 12345678901234567890}
+  {"status": "A", "qty": 5, "tags": ["x"]}
+{"status": "A", "qty": 40}`
+	tests := []struct {
+		s       string
+		input   string
+		want    string
+		matched int
+		wantErr bool
+	}{
+		{s: `status==A;qty=lt=30`, input: input, want: "{\"status\": \"A\", \"qty\": 25}\n{\"status\": \"A\", \"qty\": 5, \"tags\": [\"x\"]}\n", matched: 2},
+		{s: `qty=gt=100`, input: input, want: "{\"status\": \"B\", \"qty\": 12345678901234567890}\n", matched: 1},
+		{s: `status==C`, input: input, want: "", matched: 0},
+		{s: `status==A`, input: "", want: "", matched: 0},
+		{s: `status==A`, input: "{\"status\": \"A\"}\n{\"status\": ", want: "{\"status\": \"A\"}\n", matched: 1, wantErr: true},
+	}
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			q, err := parser.Compile(tt.s)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			var buf bytes.Buffer
+			matched, err := q.FilterNDJSON(strings.NewReader(tt.input), &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FilterNDJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if matched != tt.matched {
+				t.Errorf("FilterNDJSON() matched = %d, want %d", matched, tt.matched)
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("FilterNDJSON() got = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery_FilterCSV(t *testing.T) {
+	input := `name,qty,price,active,address.zip,note
+John,25,9.5,true,03000,
+"Doe, Jane",5,1e2,false,8000,"said ""hi"""
+Max,40,-0.5,true,3000,n/a
+`
+	tests := []struct {
+		s       string
+		input   string
+		want    string
+		matched int
+		wantErr bool
+	}{
+		{s: `qty=lt=30`, input: input, want: "name,qty,price,active,address.zip,note\nJohn,25,9.5,true,03000,\n\"Doe, Jane\",5,1e2,false,8000,\"said \"\"hi\"\"\"\n", matched: 2},
+		{s: `price=gt=50`, input: input, want: "name,qty,price,active,address.zip,note\n\"Doe, Jane\",5,1e2,false,8000,\"said \"\"hi\"\"\"\n", matched: 1},
+		{s: `active==true;address.zip=="03000"`, input: input, want: "name,qty,price,active,address.zip,note\nJohn,25,9.5,true,03000,\n", matched: 1},
+		{s: `address.zip==3000`, input: input, want: "name,qty,price,active,address.zip,note\nMax,40,-0.5,true,3000,n/a\n", matched: 1},
+		{s: `note=null=true`, input: input, want: "name,qty,price,active,address.zip,note\nJohn,25,9.5,true,03000,\n", matched: 1},
+		{s: `name==Jo*`, input: "name\n", want: "name\n", matched: 0},
+		{s: `name==Jo*`, input: "", want: "", matched: 0},
+		{s: `name==Jo*`, input: "name,qty\nJohn\n", wantErr: true},
+	}
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			q, err := parser.Compile(tt.s)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			var buf bytes.Buffer
+			matched, err := q.FilterCSV(strings.NewReader(tt.input), &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FilterCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if matched != tt.matched {
+				t.Errorf("FilterCSV() matched = %d, want %d", matched, tt.matched)
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("FilterCSV() got = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package rsql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// suggest returns the candidates which are similar to the given string,
+// ordered by similarity. Candidates are compared case-insensitive.
+func suggest(s string, candidates []string) []string {
+	type match struct {
+		candidate string
+		distance  int
+	}
+	// allow one edit per three characters, but
+	// at least one and never all of them
+	length := len([]rune(s))
+	maxDistance := minInt(length-1, maxInt(1, length/3))
+	var matches []match
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c] || c == s {
+			continue
+		}
+		seen[c] = true
+		d := editDistance(strings.ToLower(s), strings.ToLower(c))
+		if d <= maxDistance {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	var res []string
+	for _, m := range matches {
+		res = append(res, m.candidate)
+	}
+	return res
+}
+
+// editDistance returns the number of edits (insertions, deletions, substitutions
+// and transpositions of adjacent characters) needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// minInt returns the smallest of the given integers.
+func minInt(i int, is ...int) int {
+	for _, x := range is {
+		if x < i {
+			i = x
+		}
+	}
+	return i
+}
+
+// maxInt returns the largest of the given integers.
+func maxInt(i int, is ...int) int {
+	for _, x := range is {
+		if x > i {
+			i = x
+		}
+	}
+	return i
+}
+
+// withSuggestions adds the given suggestions to the error.
+func withSuggestions(err *Error, suggestions []string) *Error {
+	if len(suggestions) == 0 {
+		return err
+	}
+	err.Suggestions = suggestions
+	err.Msg = fmt.Sprintf("%s, did you mean '%s'?", err.Msg, suggestions[0])
+	return err
+}
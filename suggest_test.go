@@ -0,0 +1,141 @@
+package rsql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"status", "status", 0},
+		{"stauts", "status", 1},
+		{"statu", "status", 1},
+		{"statsu", "status", 1},
+		{"qty", "city", 2},
+		{"=gte=", "=ge=", 1},
+		{"äbc", "abc", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("editDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_suggest(t *testing.T) {
+	tests := []struct {
+		name       string
+		s          string
+		candidates []string
+		want       []string
+	}{
+		{
+			name:       "transposition",
+			s:          "stauts",
+			candidates: []string{"qty", "status", "stats"},
+			want:       []string{"status", "stats"},
+		},
+		{
+			name:       "case insensitive",
+			s:          "Status",
+			candidates: []string{"status"},
+			want:       []string{"status"},
+		},
+		{
+			name:       "operator",
+			s:          "=gte=",
+			candidates: []string{"==", "!=", "=gt=", "=ge=", "=lt=", "=le=", "=in=", "=out="},
+			want:       []string{"=gt=", "=ge="},
+		},
+		{
+			name:       "too short",
+			s:          "x",
+			candidates: []string{"a", "b"},
+		},
+		{
+			name:       "nothing similar",
+			s:          "price",
+			candidates: []string{"status", "qty"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggest(tt.s, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suggest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ProcessSuggestions(t *testing.T) {
+	parser, err := NewParser(Mongo())
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	tests := []struct {
+		name    string
+		s       string
+		options []func(*ProcessOptions) error
+		wantMsg string
+		want    []string
+	}{
+		{
+			name: "unknown key",
+			s:    "stauts==1",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"status", "qty"}),
+			},
+			wantMsg: "given key 'stauts' is not allowed, did you mean 'status'?",
+			want:    []string{"status"},
+		},
+		{
+			name:    "unknown operator",
+			s:       "qty=lte=1",
+			wantMsg: "unknown operator '=lte=', did you mean '=lt='?",
+			want:    []string{"=lt=", "=le="},
+		},
+		{
+			name: "operator not allowed",
+			s:    "email=ni=(1,2)",
+			options: []func(*ProcessOptions) error{
+				SetAllowedOperators(map[string][]string{
+					"email": {"==", "=in="},
+				}),
+			},
+			wantMsg: "operator '=ni=' is not allowed for key 'email', allowed operators are: == =in=, did you mean '=in='?",
+			want:    []string{"=in="},
+		},
+		{
+			name: "no suggestions",
+			s:    "price==1",
+			options: []func(*ProcessOptions) error{
+				SetAllowedKeys([]string{"status", "qty"}),
+			},
+			wantMsg: "given key 'price' is not allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Process(tt.s, tt.options...)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Process() error = %v, want *Error", err)
+			}
+			if e.Msg != tt.wantMsg {
+				t.Errorf("Process() error message = %v, want %v", e.Msg, tt.wantMsg)
+			}
+			if !reflect.DeepEqual(e.Suggestions, tt.want) {
+				t.Errorf("Process() suggestions = %v, want %v", e.Suggestions, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package rsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isTrue checks if the given value is true.
+func isTrue(value string) bool {
+	return strings.EqualFold(value, "true")
+}
+
+// booleanValidator returns a value transformer which checks that
+// the values of the given operators are either true or false.
+func booleanValidator(operators ...string) func(key, operator string, values []string) ([]string, error) {
+	return func(key, operator string, values []string) ([]string, error) {
+		if !containsString(operators, operator) {
+			return values, nil
+		}
+		if len(values) != 1 || !isTrue(values[0]) && !strings.EqualFold(values[0], "false") {
+			return nil, fmt.Errorf("value of operator '%s' must be true or false", operator)
+		}
+		return values, nil
+	}
+}
+
+// rangeValidator returns a value transformer which checks that the values
+// of the given operators are lists with exactly two values, the lower bound
+// and the upper bound. If both bounds are numbers, the lower bound must not
+// be greater than the upper bound. Dates are checked when they are resolved, see DateOptions.checkRange.
+func rangeValidator(operators ...string) func(key, operator string, values []string) ([]string, error) {
+	return func(key, operator string, values []string) ([]string, error) {
+		if !containsString(operators, operator) {
+			return values, nil
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("operator '%s' requires a list of two values, e.g. (1,10)", operator)
+		}
+		low, lowErr := strconv.ParseFloat(values[0], 64)
+		high, highErr := strconv.ParseFloat(values[1], 64)
+		if lowErr == nil && highErr == nil && low > high {
+			return nil, fmt.Errorf("lower bound %s of operator '%s' is greater than upper bound %s", values[0], operator, values[1])
+		}
+		return values, nil
+	}
+}
+
+// sizeValidator returns a value transformer which checks that
+// the values of the given operators are non-negative integers.
+func sizeValidator(operators ...string) func(key, operator string, values []string) ([]string, error) {
+	return func(key, operator string, values []string) ([]string, error) {
+		if !containsString(operators, operator) {
+			return values, nil
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("operator '%s' requires a single value", operator)
+		}
+		if n, err := strconv.Atoi(values[0]); err != nil || n < 0 {
+			return nil, fmt.Errorf("value of operator '%s' must be a non-negative integer", operator)
+		}
+		return values, nil
+	}
+}
@@ -0,0 +1,64 @@
+package rsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_booleanValidator(t *testing.T) {
+	validator := booleanValidator("=null=")
+	tests := []struct {
+		name     string
+		operator string
+		values   []string
+		wantErr  bool
+	}{
+		{"true", "=null=", []string{"true"}, false},
+		{"false", "=null=", []string{"False"}, false},
+		{"other operator", "==", []string{"1"}, false},
+		{"invalid", "=null=", []string{"1"}, true},
+		{"list", "=null=", []string{"true", "false"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validator("a", tt.operator, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("booleanValidator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.values) {
+				t.Errorf("booleanValidator() got = %v, want %v", got, tt.values)
+			}
+		})
+	}
+}
+
+func Test_rangeValidator(t *testing.T) {
+	validator := rangeValidator("=between=")
+	tests := []struct {
+		name     string
+		operator string
+		values   []string
+		wantErr  bool
+	}{
+		{"numbers", "=between=", []string{"10", "20.5"}, false},
+		{"equal bounds", "=between=", []string{"10", "10"}, false},
+		{"strings", "=between=", []string{"'b'", "'a'"}, false},
+		{"other operator", "=in=", []string{"1", "2", "3"}, false},
+		{"swapped bounds", "=between=", []string{"20", "10"}, true},
+		{"one value", "=between=", []string{"10"}, true},
+		{"three values", "=between=", []string{"1", "2", "3"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validator("a", tt.operator, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rangeValidator() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.values) {
+				t.Errorf("rangeValidator() got = %v, want %v", got, tt.values)
+			}
+		})
+	}
+}
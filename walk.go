@@ -0,0 +1,114 @@
+package rsql
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Walk traverses the given syntax tree in depth-first order, calling fn for each node
+// before its children. If fn returns false, the children of the node are skipped.
+// Sub-queries within values (e.g. of =elem= comparisons) are not traversed.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	var children []Node
+	switch n := node.(type) {
+	case *AndNode:
+		children = n.Children
+	case *OrNode:
+		children = n.Children
+	}
+	for _, child := range children {
+		Walk(child, fn)
+	}
+}
+
+// Rewrite returns a copy of the given syntax tree, rewritten by fn. The children of a node
+// are rewritten before the node itself, fn receives copies which can be modified and returns
+// the node to use instead, e.g. a comparison with a replaced key, or nil to remove the node.
+// Groups without children are removed and groups with a single child are replaced by the child.
+// To keep the positions of errors meaningful, the positions of replaced nodes should be kept.
+// If fn returns an error, rewriting is stopped and the error is returned as *Error
+// with the position of the node, unless it is an *Error already.
+// Sub-queries within values (e.g. of =elem= comparisons) are not rewritten.
+// If every node is removed, nil is returned, which is an empty query matching everything.
+func Rewrite(node Node, fn func(Node) (Node, error)) (Node, error) {
+	switch n := node.(type) {
+	case nil:
+		return nil, nil
+	case *AndNode:
+		children, err := rewriteChildren(n.Children, fn)
+		if err != nil || len(children) < 2 {
+			return firstNode(children), err
+		}
+		node = &AndNode{Position: n.Position, Children: children}
+	case *OrNode:
+		children, err := rewriteChildren(n.Children, fn)
+		if err != nil || len(children) < 2 {
+			return firstNode(children), err
+		}
+		node = &OrNode{Position: n.Position, Children: children}
+	case *ComparisonNode:
+		c := *n
+		node = &c
+	}
+	res, err := fn(node)
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			return nil, err
+		}
+		return nil, newError(node.Pos(), "%w", err)
+	}
+	return unwrap(res), nil
+}
+
+// rewriteChildren rewrites the given children, removing the ones replaced by nil.
+func rewriteChildren(children []Node, fn func(Node) (Node, error)) ([]Node, error) {
+	res := make([]Node, 0, len(children))
+	for _, child := range children {
+		child, err := Rewrite(child, fn)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			res = append(res, child)
+		}
+	}
+	return res, nil
+}
+
+// WithRewriters adds functions to rewrite the syntax tree of queries before they are
+// processed or compiled, see Rewrite. Other than key transformers, rewriters can
+// replace or remove whole comparisons, e.g. to inject defaults or to drop deprecated keys.
+// Rewriters are called in the given order, sub-queries of =elem= comparisons are not rewritten.
+// Rewriters must return AND, OR or comparison nodes, other node types are reported as error.
+// If the rewriters remove every comparison, the query is empty and matches everything.
+func WithRewriters(rewriters ...func(Node) (Node, error)) func(parser *Parser) error {
+	return func(parser *Parser) error {
+		for _, r := range rewriters {
+			if r == nil {
+				return fmt.Errorf("rewriter must not be nil")
+			}
+		}
+		parser.rewriters = append(parser.rewriters, rewriters...)
+		return nil
+	}
+}
+
+// rewrite rewrites the given node using the parser's rewriters.
+// Errors are added to the state.
+func (parser *Parser) rewrite(node Node, state *processState) Node {
+	for _, fn := range parser.rewriters {
+		var err error
+		node, err = Rewrite(node, fn)
+		if err != nil {
+			var e *Error
+			errors.As(err, &e)
+			state.report(e)
+			return nil
+		}
+	}
+	return node
+}
@@ -0,0 +1,214 @@
+package rsql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	node, err := Parse(`a==1;(b==2,c==3;d==4);e==5`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var keys []string
+	Walk(node, func(n Node) bool {
+		if c, ok := n.(*ComparisonNode); ok {
+			keys = append(keys, c.Key)
+		}
+		return true
+	})
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Walk() visited %v, want %v", keys, want)
+	}
+	keys = nil
+	Walk(node, func(n Node) bool {
+		if c, ok := n.(*ComparisonNode); ok {
+			keys = append(keys, c.Key)
+		}
+		// skip OR nodes
+		_, ok := n.(*OrNode)
+		return !ok
+	})
+	if want := []string{"a", "e"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Walk() visited %v, want %v", keys, want)
+	}
+	Walk(nil, func(n Node) bool {
+		t.Errorf("Walk() visited node of nil tree")
+		return true
+	})
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		fn      func(Node) (Node, error)
+		want    string
+		wantPos int
+		wantErr bool
+	}{
+		{
+			name: "rename",
+			s:    `id==1;(name==x,id=gt=2)`,
+			fn: func(n Node) (Node, error) {
+				if c, ok := n.(*ComparisonNode); ok && c.Key == "id" {
+					c.Key = "_id"
+				}
+				return n, nil
+			},
+			want: `_id==1;(name==x,_id=gt=2)`,
+		},
+		{
+			name: "remove",
+			s:    `old==1;(old==2,name==x);(old==3,old==4)`,
+			fn: func(n Node) (Node, error) {
+				if c, ok := n.(*ComparisonNode); ok && c.Key == "old" {
+					return nil, nil
+				}
+				return n, nil
+			},
+			want: `name==x`,
+		},
+		{
+			name: "inject default",
+			s:    `name==x,name==y`,
+			fn: func(n Node) (Node, error) {
+				if n.Pos() == 0 {
+					if _, ok := n.(*ComparisonNode); !ok {
+						return &AndNode{Children: []Node{n, &ComparisonNode{Key: "deleted", Operator: "==", Value: "false"}}}, nil
+					}
+				}
+				return n, nil
+			},
+			want: `(name==x,name==y);deleted==false`,
+		},
+		{
+			name: "error",
+			s:    `name==x;old==1`,
+			fn: func(n Node) (Node, error) {
+				if c, ok := n.(*ComparisonNode); ok && c.Key == "old" {
+					return nil, errors.New("key 'old' is deprecated")
+				}
+				return n, nil
+			},
+			wantPos: 8,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.s)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := Rewrite(node, tt.fn)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Rewrite() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if e, ok := err.(*Error); !ok || e.Pos != tt.wantPos {
+					t.Errorf("Rewrite() error = %#v, want position %d", err, tt.wantPos)
+				}
+				return
+			}
+			if Format(got) != tt.want {
+				t.Errorf("Rewrite() got = %v, want %v", Format(got), tt.want)
+			}
+			if Format(node) != Format(mustParse(t, tt.s)) {
+				t.Errorf("Rewrite() modified the given node: %v", Format(node))
+			}
+		})
+	}
+}
+
+func TestWithRewriters(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithRewriters(
+		func(n Node) (Node, error) {
+			if c, ok := n.(*ComparisonNode); ok && c.Key == "old" {
+				return nil, errors.New("key 'old' is deprecated")
+			}
+			return n, nil
+		},
+		func(n Node) (Node, error) {
+			if c, ok := n.(*ComparisonNode); ok && c.Key == "status" && c.Value == "any" {
+				return nil, nil
+			}
+			return n, nil
+		},
+	))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	got, err := parser.Process(`status==any;qty=gt=5`)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if want := `{ "qty": { "$gt": 5 } }`; got != want {
+		t.Errorf("Process() got = %v, want %v", got, want)
+	}
+	_, err = parser.Process(`qty=gt=5;old==1`)
+	if e, ok := err.(*Error); !ok || e.Pos != 9 {
+		t.Errorf("Process() error = %v, want error at position 9", err)
+	}
+	q, err := parser.Compile(`status==any;qty=gt=5`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if ok, _ := q.Match(map[string]interface{}{"status": "A", "qty": int64(6)}); !ok {
+		t.Errorf("Match() got = false, want true")
+	}
+	if _, err := NewParser(Mongo(), WithRewriters(nil)); err == nil {
+		t.Errorf("WithRewriters() expected error for nil rewriter")
+	}
+}
+
+// customNode is a node type unknown to the parser.
+type customNode struct {
+	Position int
+}
+
+func (n *customNode) Pos() int {
+	return n.Position
+}
+
+func (n *customNode) String() string {
+	return ""
+}
+
+func TestWithRewriters_UnsupportedNode(t *testing.T) {
+	parser, err := NewParser(Mongo(), WithRewriters(func(n Node) (Node, error) {
+		if c, ok := n.(*ComparisonNode); ok && c.Key == "custom" {
+			return &customNode{Position: c.Position}, nil
+		}
+		return n, nil
+	}))
+	if err != nil {
+		t.Fatalf("error while creating parser: %s", err)
+	}
+	want := "unsupported node type *rsql.customNode"
+	for _, s := range []string{`custom==1`, `a==1;custom==1`, `a==1,custom==1`} {
+		if _, err := parser.Process(s); !hasMessage(err, want) {
+			t.Errorf("Process(%s) error = %v, want %s", s, err, want)
+		}
+		if _, err := parser.Compile(s); !hasMessage(err, want) {
+			t.Errorf("Compile(%s) error = %v, want %s", s, err, want)
+		}
+	}
+}
+
+func mustParse(t *testing.T, s string) Node {
+	t.Helper()
+	node, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return node
+}
+
+// hasMessage checks if the given error is an *Error with the given message.
+func hasMessage(err error, msg string) bool {
+	e, ok := err.(*Error)
+	return ok && e.Msg == msg
+}
@@ -0,0 +1,91 @@
+package rsql
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// splitWildcards splits the given value at its wildcards (*).
+// Quotes around the value are removed and escaped characters
+// (e.g. \* for a literal asterisk) are unescaped.
+// The second return value is false if the value does not contain any wildcards.
+func splitWildcards(value string) ([]string, bool) {
+	value = trimQuotes(value)
+	var parts []string
+	var part strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			part.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	parts = append(parts, part.String())
+	return parts, len(parts) > 1
+}
+
+// unescapeWildcards replaces escaped asterisks (\*) within
+// a value without wildcards by literal asterisks.
+func unescapeWildcards(value string) string {
+	if !strings.Contains(value, `\*`) {
+		return value
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		if escaped {
+			if r != '*' {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if escaped {
+		b.WriteRune('\\')
+	}
+	return b.String()
+}
+
+// trimQuotes removes single or double quotes around the given value.
+func trimQuotes(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// mongoRegex returns the given wildcard parts as anchored regex,
+// encoded as json string.
+func mongoRegex(parts []string) string {
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	b, _ := json.Marshal("^" + strings.Join(parts, ".*") + "$")
+	return string(b)
+}
+
+// sqlLike returns the given wildcard parts as sql LIKE pattern,
+// escaping % and _ using a backslash.
+func sqlLike(parts []string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	for i, p := range parts {
+		parts[i] = replacer.Replace(p)
+	}
+	return strings.Join(parts, "%")
+}
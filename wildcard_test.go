@@ -0,0 +1,86 @@
+package rsql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_splitWildcards(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   []string
+		wantOk bool
+	}{
+		{
+			name:  "no wildcard",
+			value: `"John"`,
+			want:  []string{"John"},
+		},
+		{
+			name:   "prefix",
+			value:  `Jo*`,
+			want:   []string{"Jo", ""},
+			wantOk: true,
+		},
+		{
+			name:   "quoted",
+			value:  `'*oh*'`,
+			want:   []string{"", "oh", ""},
+			wantOk: true,
+		},
+		{
+			name:  "escaped asterisk",
+			value: `"a\*b"`,
+			want:  []string{"a*b"},
+		},
+		{
+			name:   "escaped asterisk and wildcard",
+			value:  `"a\**"`,
+			want:   []string{"a*", ""},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := splitWildcards(tt.value)
+			if ok != tt.wantOk {
+				t.Errorf("splitWildcards() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitWildcards() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_unescapeWildcards(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`"John"`, `"John"`},
+		{`"a\*b"`, `"a*b"`},
+		{`"a\"b\*"`, `"a\"b*"`},
+		{`"a\\"`, `"a\\"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := unescapeWildcards(tt.value); got != tt.want {
+				t.Errorf("unescapeWildcards() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mongoRegex(t *testing.T) {
+	if got, want := mongoRegex([]string{"a.b", `"c`, ""}), `"^a\\.b.*\"c.*$"`; got != want {
+		t.Errorf("mongoRegex() = %v, want %v", got, want)
+	}
+}
+
+func Test_sqlLike(t *testing.T) {
+	if got, want := sqlLike([]string{"10%", "a_b", `c\`}), `10\%%a\_b%c\\`; got != want {
+		t.Errorf("sqlLike() = %v, want %v", got, want)
+	}
+}